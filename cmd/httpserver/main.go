@@ -2,6 +2,7 @@ package main
 
 import (
 	"crypto/sha256"
+	"encoding/json"
 	"fmt"
 	"httpfromtcp/internal/headers"
 	"httpfromtcp/internal/request"
@@ -9,18 +10,26 @@ import (
 	"httpfromtcp/internal/server"
 	"io"
 	"log"
+	"maps"
 	"net/http"
+	"net/url"
 	"os"
 	"os/signal"
+	"slices"
 	"strconv"
-	"strings"
 	"syscall"
 )
 
 const port = 42069
 
+// maxBodyBytes caps request bodies. The parser streams bodies of any length,
+// but none of the handlers here needs more than this.
+const maxBodyBytes = 10 << 20
+
 func main() {
-	server, err := server.Serve(port, testHandler)
+	config := server.DefaultConfig()
+	config.Parser.MaxBodyBytes = maxBodyBytes
+	server, err := server.ServeWithConfig(port, testHandler, config)
 	if err != nil {
 		log.Fatalf("Error starting server: %v", err)
 	}
@@ -34,97 +43,150 @@ func main() {
 }
 
 func testHandler(w *response.Writer, req *request.Request) {
-	if strings.HasPrefix(req.RequestLine.RequestTarget, "/httpbin") {
+	if req.MatchPath("/httpbin/{path...}") {
 		httpbinProxyHandler(w, req)
 		return
 	}
-	if req.RequestLine.RequestTarget == "/yourproblem" {
-		handle400(w, nil)
+	if req.Path() == "/yourproblem" {
+		handle400(w, req)
 		return
 	}
-	if req.RequestLine.RequestTarget == "/myproblem" {
+	if req.Path() == "/myproblem" {
 		handle500(w, req)
 		return
 	}
-	if req.RequestLine.RequestTarget == "/video" {
+	if req.Path() == "/video" {
 		handleVideo(w, req)
 		return
 	}
 	handle200(w, req)
 }
 
-func handle200(w *response.Writer, _ *request.Request) {
-	res := []byte(`<html>
+// page is a canned response that can be rendered as HTML or JSON.
+type page struct {
+	status  response.StatusCode
+	title   string
+	heading string
+	message string
+}
+
+var (
+	page200 = page{response.HttpOK, "200 OK", "Success!", "Your request was an absolute banger."}
+	page400 = page{response.HttpNotFoud, "400 Bad Request", "Bad Request", "Your request honestly kinda sucked."}
+	page500 = page{response.HttpServerError, "500 Internal Server Error", "Internal Server Error", "Okay, you know what? This one is on me."}
+)
+
+func handle200(w *response.Writer, req *request.Request) {
+	writePage(w, req, page200)
+}
+
+func handle400(w *response.Writer, req *request.Request) {
+	writePage(w, req, page400)
+}
+
+func handle500(w *response.Writer, req *request.Request) {
+	writePage(w, req, page500)
+}
+
+// writePage sends p as HTML or JSON, whichever the client prefers. Clients
+// that accept neither get 406, unless p is an error page anyway, which is
+// then sent as HTML.
+func writePage(w *response.Writer, req *request.Request, p page) {
+	contentType, err := req.NegotiateContentType("text/html", "application/json")
+	if err != nil {
+		if p.status == response.HttpOK {
+			handle406(w)
+			return
+		}
+		contentType = "text/html"
+	}
+
+	var res []byte
+	if contentType == "application/json" {
+		res, _ = json.Marshal(map[string]any{
+			"status":  int(p.status),
+			"title":   p.title,
+			"message": p.message,
+		})
+	} else {
+		res = []byte(fmt.Sprintf(`<html>
   <head>
-    <title>200 OK</title>
+    <title>%s</title>
   </head>
   <body>
-    <h1>Success!</h1>
-    <p>Your request was an absolute banger.</p>
+    <h1>%s</h1>
+    <p>%s</p>
   </body>
-</html>`)
-	w.WriteStatusLine(response.HttpOK)
-	headers := response.GetDefaultHeaders(len(res))
-	headers.Overwrite("Content-Type", "text/html")
-	w.WriteHeaders(headers)
-	w.WriteBody(res)
-}
+</html>`, p.title, p.heading, p.message))
+	}
 
-func handle400(w *response.Writer, _ *request.Request) {
-	res := []byte(`<html>
- <head>
-   <title>400 Bad Request</title>
- </head>
- <body>
-   <h1>Bad Request</h1>
-   <p>Your request honestly kinda sucked.</p>
- </body>
-</html>`)
-	w.WriteStatusLine(response.HttpNotFoud)
+	w.WriteStatusLine(p.status)
 	headers := response.GetDefaultHeaders(len(res))
-	headers.Overwrite("Content-Type", "text/html")
+	headers.Set("Content-Type", contentType)
+	headers.Set("Vary", "Accept")
 	w.WriteHeaders(headers)
 	w.WriteBody(res)
 }
 
-func handle500(w *response.Writer, _ *request.Request) {
-	res := []byte(`<html>
-  <head>
-    <title>500 Internal Server Error</title>
-  </head>
-  <body>
-    <h1>Internal Server Error</h1>
-    <p>Okay, you know what? This one is on me.</p>
-  </body>
-</html>`)
-	w.WriteStatusLine(response.HttpServerError)
+func handle406(w *response.Writer) {
+	res := []byte("Available representations: text/html, application/json\n")
+	w.WriteStatusLine(response.HttpNotAcceptable)
 	headers := response.GetDefaultHeaders(len(res))
-	headers.Overwrite("Content-Type", "text/html")
+	headers.Set("Vary", "Accept")
 	w.WriteHeaders(headers)
 	w.WriteBody(res)
 }
 
+// httpbinURL is the upstream /httpbin/ requests are proxied to.
+var httpbinURL = url.URL{Scheme: "https", Host: "httpbin.org"}
+
 func httpbinProxyHandler(w *response.Writer, req *request.Request) {
 
-	path := strings.TrimPrefix(req.RequestLine.RequestTarget, "/httpbin")
+	upstream := url.URL{
+		Scheme:   httpbinURL.Scheme,
+		Host:     httpbinURL.Host,
+		Path:     "/" + req.PathValue("path"),
+		RawQuery: req.URL.RawQuery,
+	}
 	fmt.Println(req.RequestLine.RequestTarget)
 
-	binResp, err := http.Get(fmt.Sprintf("https://httpbin.org%s", path))
-	fmt.Printf("https://httpbin.org/%s\n", path)
+	// The upstream request is tied to the client's, so a client hanging up
+	// stops the download and the copy loop below.
+	binReq, err := http.NewRequestWithContext(req.Context(), http.MethodGet, upstream.String(), nil)
+	if err != nil {
+		handle500(w, req)
+		return
+	}
+	forward := req.Headers.Clone()
+	forward.RemoveHopByHop()
+	forward.Del("Host")
+	forward.Del("Content-Length")
+	for name, value := range forward.All() {
+		binReq.Header.Add(name, value)
+	}
+	binResp, err := http.DefaultClient.Do(binReq)
+	fmt.Println(upstream.String())
 	if err != nil {
 		handle500(w, req)
 		return
 	}
 	defer binResp.Body.Close()
 
-	w.WriteStatusLine(http.StatusOK)
+	// Relay the upstream status, since forwarded Range and conditional
+	// headers can turn it into 206 or 304.
+	status := response.StatusCode(binResp.StatusCode)
+	w.WriteStatusLine(status)
 
-	// Set headers
-	h := response.GetDefaultHeaders(0)
-	h.Delete("Content-Length")
-	h.Overwrite("Transfer-Encoding", "chunked")
-	h.Set("Trailer", "X-Content-SHA256")
-	h.Set("Trailer", "X-Content-Length")
+	// Pass on the upstream's end-to-end fields and frame the body ourselves.
+	h := proxyHeaders(binResp.Header)
+	h.Del("Content-Length")
+	if status == http.StatusNoContent || status == http.StatusNotModified {
+		w.WriteHeaders(h)
+		return
+	}
+	h.Set("Transfer-Encoding", "chunked")
+	h.Add("Trailer", "X-Content-SHA256")
+	h.Add("Trailer", "X-Content-Length")
 	w.WriteHeaders(h)
 
 	b := make([]byte, 1024)
@@ -163,6 +225,19 @@ func httpbinProxyHandler(w *response.Writer, req *request.Request) {
 	}
 }
 
+// proxyHeaders copies the fields of an upstream response that may be
+// forwarded, in a stable order.
+func proxyHeaders(upstream http.Header) *headers.Headers {
+	h := headers.NewHeaders()
+	for _, name := range slices.Sorted(maps.Keys(upstream)) {
+		for _, value := range upstream[name] {
+			h.Add(name, value)
+		}
+	}
+	h.RemoveHopByHop()
+	return h
+}
+
 func handleVideo(w *response.Writer, req *request.Request) {
 	videoFile, err := os.Open("assets/vim.mp4")
 	if err != nil {
@@ -170,50 +245,19 @@ func handleVideo(w *response.Writer, req *request.Request) {
 		handle500(w, req)
 		return
 	}
-
-	w.WriteStatusLine(http.StatusOK)
-
-	// Set headers
-	h := response.GetDefaultHeaders(0)
-	h.Delete("Content-Length")
-	h.Overwrite("Content-Type", "video/mp4")
-	h.Overwrite("Transfer-Encoding", "chunked")
-	h.Set("Trailer", "X-Content-SHA256")
-	h.Set("Trailer", "X-Content-Length")
-	w.WriteHeaders(h)
-
-	b := make([]byte, 1024)
-	lenResp := 0
-	hashResp := sha256.New()
-
-	for {
-		n, err := videoFile.Read(b)
-		fmt.Println("bytes sent: ", n)
-		if n > 0 {
-			if _, writeErr := w.WriteChunkedBody(b[:n]); writeErr != nil {
-				fmt.Printf("Write error: %s\n", writeErr)
-			}
-			lenResp += n
-			hashResp.Write(b[:n])
-		}
-		if err != nil {
-			if err != io.EOF {
-				fmt.Printf("Read error: %s\n", err)
-				break
-			}
-			break
-		}
-	}
-	_, err = w.WriteChunkedBodyEnd()
+	defer videoFile.Close()
+	info, err := videoFile.Stat()
 	if err != nil {
-		fmt.Printf("Error writing chunked body end: %v", err)
+		fmt.Println(err)
+		handle500(w, req)
+		return
 	}
 
-	trailers := headers.NewHeaders()
-	trailers.Set("X-Content-SHA256", fmt.Sprintf("%x", hashResp.Sum(nil)))
-	trailers.Set("X-Content-Length", strconv.Itoa(lenResp))
-	err = w.WriteTrailers(trailers)
-	if err != nil {
-		fmt.Printf("Error writing Trailers: %v", err)
+	h := headers.NewHeaders()
+	h.Set("Content-Type", "video/mp4")
+	h.SetTime("Last-Modified", info.ModTime())
+	h.Set("ETag", fmt.Sprintf(`"%x-%x"`, info.ModTime().UnixNano(), info.Size()))
+	if err := w.ServeContent(req.RequestLine.Method, req.Headers, h, videoFile, info.Size()); err != nil {
+		fmt.Printf("Error serving video: %v\n", err)
 	}
 }
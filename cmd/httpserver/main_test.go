@@ -0,0 +1,61 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"httpfromtcp/internal/request"
+	"httpfromtcp/internal/response"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHttpbinProxyStatus(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/status/404":
+			http.Error(w, "nothing here", http.StatusNotFound)
+		case "/etag":
+			w.Header().Set("ETag", `"v1"`)
+			w.WriteHeader(http.StatusNotModified)
+		}
+	}))
+	t.Cleanup(upstream.Close)
+	u, err := url.Parse(upstream.URL)
+	require.NoError(t, err)
+	saved := httpbinURL
+	httpbinURL = *u
+	t.Cleanup(func() { httpbinURL = saved })
+
+	proxy := func(target string) *http.Response {
+		req, err := request.RequestFromReader(strings.NewReader("GET " + target + " HTTP/1.1\r\nHost: localhost\r\n\r\n"))
+		require.NoError(t, err)
+		var buf bytes.Buffer
+		w := response.NewWriter(&buf)
+		w.SetMethod(req.RequestLine.Method)
+		testHandler(w, req)
+		require.NoError(t, w.Finish())
+		resp, err := http.ReadResponse(bufio.NewReader(&buf), &http.Request{Method: req.RequestLine.Method})
+		require.NoError(t, err)
+		return resp
+	}
+
+	// Test: A non-200 upstream status is relayed with its reason phrase
+	resp := proxy("/httpbin/status/404")
+	assert.Equal(t, "404 Not Found", resp.Status)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "nothing here\n", string(body))
+
+	// Test: 304 is relayed without a body
+	resp = proxy("/httpbin/etag")
+	assert.Equal(t, "304 Not Modified", resp.Status)
+	assert.Equal(t, `"v1"`, resp.Header.Get("ETag"))
+	assert.Empty(t, resp.Header.Get("Transfer-Encoding"))
+}
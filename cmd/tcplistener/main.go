@@ -1,6 +1,7 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"httpfromtcp/internal/request"
 	"log"
@@ -23,13 +24,23 @@ func main() {
 		}
 		fmt.Printf("connection accepted on %s\n", port)
 		fmt.Print("==============================\n")
-		req, err := request.RequestFromReader(conn)
+		req, err := request.RequestFromReaderWithConfig(conn, request.LenientParserConfig())
 		if err != nil {
-			log.Fatal(err)
+			var parseErr *request.ParseError
+			if errors.As(err, &parseErr) {
+				fmt.Printf("Malformed request at byte %d while parsing %s: %v\n", parseErr.Offset, parseErr.State, parseErr.Err)
+			} else {
+				log.Printf("error reading request: %v", err)
+			}
+			conn.Close()
+			continue
 		}
 		fmt.Printf("Request line:\n- Method: %s\n- Target: %s\n- Version: %s\n", req.RequestLine.Method, req.RequestLine.RequestTarget, req.RequestLine.HttpVersion)
+		if req.Leniencies != 0 {
+			fmt.Printf("- Leniencies: %s\n", req.Leniencies)
+		}
 		fmt.Println("Headers:")
-		for key, value := range req.Headers {
+		for key, value := range req.Headers.All() {
 			fmt.Printf("- %s: %s\n", key, value)
 		}
 	}
@@ -0,0 +1,60 @@
+package headers
+
+import "testing"
+
+var benchHeaderBlock = []byte("Host: localhost:42069\r\n" +
+	"User-Agent: curl/8.5.0\r\n" +
+	"Accept: */*\r\n" +
+	"Content-Type: application/json\r\n" +
+	"Content-Length: 1024\r\n" +
+	"X-Custom-Header: some value\r\n" +
+	"\r\n")
+
+func BenchmarkParse(b *testing.B) {
+	b.ReportAllocs()
+	b.SetBytes(int64(len(benchHeaderBlock)))
+	for i := 0; i < b.N; i++ {
+		h := NewHeaders()
+		data := benchHeaderBlock
+		for {
+			n, done, err := h.Parse(data)
+			if err != nil {
+				b.Fatal(err)
+			}
+			data = data[n:]
+			if done {
+				break
+			}
+		}
+	}
+}
+
+func BenchmarkParseString(b *testing.B) {
+	b.ReportAllocs()
+	b.SetBytes(int64(len(benchHeaderBlock)))
+	for i := 0; i < b.N; i++ {
+		h := NewHeaders()
+		data := string(benchHeaderBlock)
+		for {
+			n, done, _, err := h.ParseString(data, ParseOptions{Strict: true})
+			if err != nil {
+				b.Fatal(err)
+			}
+			data = data[n:]
+			if done {
+				break
+			}
+		}
+	}
+}
+
+func BenchmarkGet(b *testing.B) {
+	b.ReportAllocs()
+	h := NewHeaders()
+	h.Set("Content-Type", "application/json")
+	for i := 0; i < b.N; i++ {
+		if _, ok := h.Get("Content-Type"); !ok {
+			b.Fatal("missing header")
+		}
+	}
+}
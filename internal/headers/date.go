@@ -0,0 +1,30 @@
+package headers
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// TimeFormat is the IMF-fixdate format of HTTP dates. Times have to be in
+// UTC before formatting with it.
+const TimeFormat = "Mon, 02 Jan 2006 15:04:05 GMT"
+
+// Obsolete date formats that recipients still have to accept.
+const (
+	rfc850Format  = "Monday, 02-Jan-06 15:04:05 GMT"
+	asctimeFormat = "Mon Jan _2 15:04:05 2006"
+)
+
+var ErrInvalidDate = errors.New("invalid HTTP date")
+
+// ParseTime parses an HTTP-date in any of the three formats RFC 9110 section
+// 5.6.7 requires recipients to accept.
+func ParseTime(value string) (time.Time, error) {
+	for _, layout := range []string{TimeFormat, rfc850Format, asctimeFormat} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%w: %q", ErrInvalidDate, value)
+}
@@ -0,0 +1,28 @@
+package headers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTime(t *testing.T) {
+	want := time.Date(1994, 11, 6, 8, 49, 37, 0, time.UTC)
+	for _, value := range []string{
+		"Sun, 06 Nov 1994 08:49:37 GMT",
+		"Sunday, 06-Nov-94 08:49:37 GMT",
+		"Sun Nov  6 08:49:37 1994",
+	} {
+		got, err := ParseTime(value)
+		require.NoError(t, err, value)
+		assert.True(t, want.Equal(got), value)
+	}
+	assert.Equal(t, "Sun, 06 Nov 1994 08:49:37 GMT", want.Format(TimeFormat))
+
+	for _, value := range []string{"", "yesterday", "Sun, 06 Nov 1994 08:49:37 PST", "1994-11-06T08:49:37Z"} {
+		_, err := ParseTime(value)
+		require.ErrorIs(t, err, ErrInvalidDate, value)
+	}
+}
@@ -0,0 +1,55 @@
+package headers
+
+import "strings"
+
+// ETagsMatch compares two entity tags. The strong comparison needs both to be
+// strong and identical; the weak one ignores the W/ prefix.
+func ETagsMatch(a, b string, weak bool) bool {
+	if !weak {
+		return a == b && a != "" && !strings.HasPrefix(a, "W/")
+	}
+	return a != "" && strings.TrimPrefix(a, "W/") == strings.TrimPrefix(b, "W/")
+}
+
+// MatchETagList reports whether etag matches any entity tag in list, the
+// value of an If-Match or If-None-Match field. exists tells whether there is
+// a current representation at all; "*" matches any that exists, whether or
+// not it has an entity tag.
+func MatchETagList(list, etag string, exists, weak bool) bool {
+	if strings.TrimSpace(list) == "*" {
+		return exists
+	}
+	for _, candidate := range splitETags(list) {
+		if ETagsMatch(candidate, etag, weak) {
+			return true
+		}
+	}
+	return false
+}
+
+// splitETags splits a list of entity tags, which may contain commas inside
+// their quotes. Malformed elements are skipped.
+func splitETags(list string) []string {
+	var tags []string
+	for {
+		list = strings.TrimLeft(list, " \t,")
+		if list == "" {
+			return tags
+		}
+		start := 0
+		if strings.HasPrefix(list, "W/") {
+			start = 2
+		}
+		if len(list) <= start || list[start] != '"' {
+			_, list, _ = strings.Cut(list, ",")
+			continue
+		}
+		end := strings.IndexByte(list[start+1:], '"')
+		if end == -1 {
+			return tags
+		}
+		end += start + 2
+		tags = append(tags, list[:end])
+		list = list[end:]
+	}
+}
@@ -0,0 +1,37 @@
+package headers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestETagsMatch(t *testing.T) {
+	tests := []struct {
+		a, b         string
+		strong, weak bool
+	}{
+		{`W/"1"`, `W/"1"`, false, true},
+		{`W/"1"`, `W/"2"`, false, false},
+		{`W/"1"`, `"1"`, false, true},
+		{`"1"`, `"1"`, true, true},
+		{`"1"`, `"2"`, false, false},
+		{``, ``, false, false},
+	}
+	for _, tc := range tests {
+		assert.Equal(t, tc.strong, ETagsMatch(tc.a, tc.b, false), "%s %s strong", tc.a, tc.b)
+		assert.Equal(t, tc.weak, ETagsMatch(tc.a, tc.b, true), "%s %s weak", tc.a, tc.b)
+	}
+}
+
+func TestMatchETagList(t *testing.T) {
+	assert.True(t, MatchETagList(`"a", "b,c", W/"d"`, `"b,c"`, true, false))
+	assert.True(t, MatchETagList(`"a", "b,c", W/"d"`, `"d"`, true, true))
+	assert.False(t, MatchETagList(`"a", "b,c", W/"d"`, `"d"`, true, false))
+	assert.False(t, MatchETagList(`"a", "b,c"`, `"c"`, true, true))
+	assert.True(t, MatchETagList(`bogus, "a"`, `"a"`, true, false))
+	assert.True(t, MatchETagList(`*`, `"x"`, true, false))
+	assert.True(t, MatchETagList(`*`, ``, true, false))
+	assert.False(t, MatchETagList(`*`, ``, false, false))
+	assert.False(t, MatchETagList(`"unterminated`, `"unterminated"`, true, true))
+}
@@ -1,86 +1,349 @@
 package headers
 
 import (
-	"bytes"
+	"errors"
 	"fmt"
+	"iter"
+	"slices"
 	"strings"
 	"unicode"
 )
 
-type Headers map[string]string
+// Headers holds the field lines of a header or trailer section in the order
+// they were added. Names keep the casing they were given and are matched
+// case-insensitively. A nil *Headers reads as empty.
+type Headers struct {
+	lines []fieldLine
+}
+
+type fieldLine struct {
+	name  string
+	value string
+}
 
 const crlf = "\r\n"
 
-func NewHeaders() Headers {
-	h := make(Headers)
-	return h
+var (
+	ErrInvalidHeaderName  = errors.New("invalid header name")
+	ErrInvalidHeaderValue = errors.New("invalid header value")
+	ErrMalformedHeader    = errors.New("malformed header line")
+	ErrObsFold            = errors.New("obsolete line folding")
+	ErrBareLineEnding     = errors.New("bare CR or LF")
+)
+
+// Leniency selects non-conforming syntax that ParseWithOptions accepts
+// anyway, for clients that cannot be fixed.
+type Leniency uint8
+
+const (
+	// LenientBareLF ends lines at a bare LF as well as at CRLF.
+	LenientBareLF Leniency = 1 << iota
+	// LenientObsFold unfolds obs-fold continuation lines into the value of
+	// the field they follow, joined by a single space.
+	LenientObsFold
+)
+
+type ParseOptions struct {
+	// Strict rejects field lines that different parsers are known to read
+	// differently: obs-fold continuations and other lines starting with
+	// whitespace, bare CR or LF, and control characters in values.
+	// Leniencies take precedence over Strict for the syntax they cover.
+	Strict  bool
+	Lenient Leniency
 }
 
-func (h Headers) Parse(data []byte) (n int, done bool, err error) {
-	idx := bytes.Index(data, []byte(crlf))
+func NewHeaders() *Headers {
+	return &Headers{}
+}
+
+// initialLines is the room the first added line makes, enough for most
+// sections to be stored without growing the slice again.
+const initialLines = 8
+
+// appendLine adds a line at the end. Names are stored as given, so a name
+// sliced out of a parsed section costs no allocation of its own.
+func (h *Headers) appendLine(name, value string) {
+	if h.lines == nil {
+		h.lines = make([]fieldLine, 0, initialLines)
+	}
+	h.lines = append(h.lines, fieldLine{name, value})
+}
+
+// NewHeadersSize returns empty headers with room for size field lines.
+func NewHeadersSize(size int) *Headers {
+	return &Headers{lines: make([]fieldLine, 0, size)}
+}
+
+func (h *Headers) Parse(data []byte) (n int, done bool, err error) {
+	n, done, _, err = h.ParseWithOptions(data, ParseOptions{})
+	return n, done, err
+}
+
+// ParseWithOptions parses one field line, together with its continuation
+// lines when obs-fold is allowed. used reports the leniencies the line
+// depended on.
+func (h *Headers) ParseWithOptions(data []byte, opts ParseOptions) (n int, done bool, used Leniency, err error) {
+	f, ok := scanField(data, opts)
+	if !ok {
+		return 0, false, 0, nil
+	}
+	if f.end == 0 {
+		return f.next, true, f.used, nil
+	}
+	if err := h.parseField(string(data[:f.end]), f, opts); err != nil {
+		return 0, false, 0, err
+	}
+	return f.next, false, f.used, nil
+}
+
+// ParseString is ParseWithOptions for data that is already a string. Names
+// and values are sliced out of data instead of being copied, so converting
+// a whole header block once and parsing it with ParseString allocates far
+// less than parsing it line by line.
+func (h *Headers) ParseString(data string, opts ParseOptions) (n int, done bool, used Leniency, err error) {
+	f, ok := scanField(data, opts)
+	if !ok {
+		return 0, false, 0, nil
+	}
+	if f.end == 0 {
+		return f.next, true, f.used, nil
+	}
+	if err := h.parseField(data[:f.end], f, opts); err != nil {
+		return 0, false, 0, err
+	}
+	return f.next, false, f.used, nil
+}
+
+// field locates one field in the input: its first line ends at lineEnd, its
+// continuation lines at end, and the next field starts at next.
+type field struct {
+	lineEnd int
+	lineN   int
+	end     int
+	next    int
+	used    Leniency
+}
+
+func scanField[T string | []byte](data T, opts ParseOptions) (field, bool) {
+	allowBareLF := opts.Lenient&LenientBareLF != 0
+	var f field
+	idx, n := findLineEnd(data, allowBareLF)
 	if idx == -1 {
-		return 0, false, nil
+		return f, false
+	}
+	if n-idx == 1 {
+		f.used |= LenientBareLF
 	}
-	if idx == 0 {
-		return len([]byte(crlf)), true, nil
+	f.lineEnd, f.lineN, f.end, f.next = idx, n, idx, n
+	if idx == 0 || opts.Lenient&LenientObsFold == 0 {
+		return f, true
+	}
+	for {
+		// A continuation can only be ruled out once the next line has
+		// started.
+		if f.next >= len(data) {
+			return f, false
+		}
+		if data[f.next] != ' ' && data[f.next] != '\t' {
+			return f, true
+		}
+		contIdx, contN := findLineEnd(data[f.next:], allowBareLF)
+		if contIdx == -1 {
+			return f, false
+		}
+		if contN-contIdx == 1 {
+			f.used |= LenientBareLF
+		}
+		f.end = f.next + contIdx
+		f.next += contN
+		f.used |= LenientObsFold
+	}
+}
+
+func (h *Headers) parseField(text string, f field, opts ParseOptions) error {
+	line := text[:f.lineEnd]
+	if opts.Strict {
+		if line[0] == ' ' || line[0] == '\t' {
+			return ErrObsFold
+		}
+		if strings.ContainsAny(line, "\r\n") {
+			return ErrBareLineEnding
+		}
 	}
-	idxSep := bytes.Index(data, []byte(":"))
+	idxSep := strings.IndexByte(line, ':')
 	if idxSep == -1 {
-		return 0, false, fmt.Errorf("no colon found")
+		return fmt.Errorf("%w: no colon found", ErrMalformedHeader)
 	}
-	key := string(data[:idxSep])
-	key = strings.TrimLeft(key, " ")
-	err = validateKeyWhitespace(key)
-	if err != nil {
-		return 0, false, err
+	key := strings.TrimLeft(line[:idxSep], " ")
+	if err := validateKeyWhitespace(key); err != nil {
+		return err
 	}
-	key = strings.TrimSpace(key)
-	value := string(data[idxSep+1 : idx])
-	value = strings.TrimSpace(value)
-	err = h.Set(key, value)
-	if err != nil {
-		return 0, false, err
+	if !isValidKey(key) {
+		return fmt.Errorf("%w: %q", ErrInvalidHeaderName, key)
+	}
+	value := strings.TrimSpace(line[idxSep+1:])
+
+	if f.end > f.lineEnd {
+		allowBareLF := opts.Lenient&LenientBareLF != 0
+		rest := text[f.lineN:]
+		for rest != "" {
+			var cont string
+			idx, n := findLineEnd(rest, allowBareLF)
+			if idx == -1 {
+				cont, rest = rest, ""
+			} else {
+				cont, rest = rest[:idx], rest[n:]
+			}
+			cont = strings.TrimSpace(cont)
+			if cont != "" {
+				if value != "" {
+					value += " "
+				}
+				value += cont
+			}
+		}
+	}
+
+	if opts.Strict && !isValidValue(value) {
+		return fmt.Errorf("%w: control character in %s", ErrInvalidHeaderValue, key)
 	}
-	return idx + len([]byte(crlf)), false, nil
+	h.appendLine(key, value)
+	return nil
 }
 
-func (h Headers) Set(key, value string) error {
-	if !isValidKey(key) {
-		return fmt.Errorf("invalid key characters")
+// findLineEnd returns the length of the first line in data and the offset
+// just past its terminator, or -1 if the line is not complete yet. Unless
+// allowBareLF is set a line only ends at CRLF.
+func findLineEnd[T string | []byte](data T, allowBareLF bool) (int, int) {
+	for i := 0; i < len(data); i++ {
+		if data[i] != '\n' {
+			continue
+		}
+		if i > 0 && data[i-1] == '\r' {
+			return i - 1, i + 1
+		}
+		if allowBareLF {
+			return i, i + 1
+		}
 	}
-	key = strings.ToLower(key)
-	if _, ok := h[key]; ok {
-		h[key] = h[key] + ", " + value
+	return -1, 0
+}
+
+// Get returns the value of the named field. A field sent on several lines
+// is returned as one value, the lines joined by ", ".
+func (h *Headers) Get(name string) (string, bool) {
+	if h == nil {
+		return "", false
+	}
+	value, found := "", false
+	for _, line := range h.lines {
+		if !strings.EqualFold(line.name, name) {
+			continue
+		}
+		if found {
+			value += ", " + line.value
+		} else {
+			value, found = line.value, true
+		}
+	}
+	return value, found
+}
+
+// Values returns the value of each line of the named field, in order. Use it
+// for fields such as Set-Cookie that cannot be combined.
+func (h *Headers) Values(name string) []string {
+	if h == nil {
 		return nil
 	}
-	h[key] = value
+	var values []string
+	for _, line := range h.lines {
+		if strings.EqualFold(line.name, name) {
+			values = append(values, line.value)
+		}
+	}
+	return values
+}
+
+// Add appends a field line, keeping any lines of the same name.
+func (h *Headers) Add(name, value string) error {
+	if !isValidKey(name) {
+		return fmt.Errorf("%w: %q", ErrInvalidHeaderName, name)
+	}
+	h.appendLine(name, value)
 	return nil
 }
 
-func (h Headers) Overwrite(key, value string) error {
-	if !isValidKey(key) {
-		return fmt.Errorf("invalid key characters")
+// Set replaces the lines of the named field with a single one. It takes the
+// place of the first line it replaces, or is appended if there was none.
+func (h *Headers) Set(name, value string) error {
+	if !isValidKey(name) {
+		return fmt.Errorf("%w: %q", ErrInvalidHeaderName, name)
+	}
+	for i, line := range h.lines {
+		if strings.EqualFold(line.name, name) {
+			h.lines[i] = fieldLine{name, value}
+			h.lines = append(h.lines[:i+1], deleteLines(h.lines[i+1:], name)...)
+			return nil
+		}
 	}
-	key = strings.ToLower(key)
-	h[key] = value
+	h.appendLine(name, value)
 	return nil
 }
 
-func (h Headers) Delete(key string) {
-	key = strings.ToLower(key)
-	delete(h, key)
+// Del removes every line of the named field.
+func (h *Headers) Del(name string) {
+	if h == nil {
+		return
+	}
+	h.lines = deleteLines(h.lines, name)
+}
+
+func deleteLines(lines []fieldLine, name string) []fieldLine {
+	kept := lines[:0]
+	for _, line := range lines {
+		if !strings.EqualFold(line.name, name) {
+			kept = append(kept, line)
+		}
+	}
+	clear(lines[len(kept):])
+	return kept
+}
+
+// All iterates over the field lines in order, yielding each name as it was
+// given and its value.
+func (h *Headers) All() iter.Seq2[string, string] {
+	return func(yield func(string, string) bool) {
+		if h == nil {
+			return
+		}
+		for _, line := range h.lines {
+			if !yield(line.name, line.value) {
+				return
+			}
+		}
+	}
+}
+
+// Len returns the number of field lines.
+func (h *Headers) Len() int {
+	if h == nil {
+		return 0
+	}
+	return len(h.lines)
 }
 
-func (h Headers) Get(key string) (string, bool) {
-	key = strings.ToLower(key)
-	value, ok := h[key]
-	return value, ok
+// Clone returns a copy of h that can be changed independently.
+func (h *Headers) Clone() *Headers {
+	if h == nil {
+		return NewHeaders()
+	}
+	return &Headers{lines: slices.Clone(h.lines)}
 }
 
 func validateKeyWhitespace(key string) error {
 	for _, r := range key {
 		if unicode.IsSpace(r) {
-			return fmt.Errorf("malformed key with space")
+			return fmt.Errorf("%w: whitespace in %q", ErrInvalidHeaderName, key)
 		}
 	}
 	return nil
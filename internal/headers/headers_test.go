@@ -104,7 +104,7 @@ func TestHeaderParse(t *testing.T) {
 	assert.False(t, done)
 }
 
-func (h Headers) loopHelper(data []byte) (int, bool, error) {
+func (h *Headers) loopHelper(data []byte) (int, bool, error) {
 	done := false
 	n := 0
 	var nloc int
@@ -118,7 +118,153 @@ func (h Headers) loopHelper(data []byte) (int, bool, error) {
 	}
 	return n, done, nil
 }
-func (h Headers) testGet(key string) string {
+func (h *Headers) testGet(key string) string {
 	value, _ := h.Get(key)
 	return value
 }
+
+func TestHeaderParseStrict(t *testing.T) {
+	strict := ParseOptions{Strict: true}
+
+	// Test: Colon is only looked for on the current line
+	headers := NewHeaders()
+	_, _, err := headers.Parse([]byte("X-No-Colon\r\nHost: localhost:42069\r\n\r\n"))
+	require.ErrorIs(t, err, ErrMalformedHeader)
+
+	// Test: Valid header in strict mode
+	headers = NewHeaders()
+	n, done, _, err := headers.ParseWithOptions([]byte("Host: localhost:42069\r\n\r\n"), strict)
+	require.NoError(t, err)
+	assert.Equal(t, 23, n)
+	assert.False(t, done)
+	assert.Equal(t, "localhost:42069", headers.testGet("Host"))
+
+	// Test: Leading whitespace is obs-fold
+	headers = NewHeaders()
+	_, _, _, err = headers.ParseWithOptions([]byte(" Host: localhost:42069\r\n\r\n"), strict)
+	require.ErrorIs(t, err, ErrObsFold)
+	headers = NewHeaders()
+	_, _, _, err = headers.ParseWithOptions([]byte("\tHost: localhost:42069\r\n\r\n"), strict)
+	require.ErrorIs(t, err, ErrObsFold)
+
+	// Test: Bare LF and CR
+	headers = NewHeaders()
+	_, _, _, err = headers.ParseWithOptions([]byte("Host: a\nX-Injected: b\r\n\r\n"), strict)
+	require.ErrorIs(t, err, ErrBareLineEnding)
+	headers = NewHeaders()
+	_, _, _, err = headers.ParseWithOptions([]byte("Host: a\rb\r\n\r\n"), strict)
+	require.ErrorIs(t, err, ErrBareLineEnding)
+
+	// Test: Control characters in values, tabs and obs-text are fine
+	headers = NewHeaders()
+	_, _, _, err = headers.ParseWithOptions([]byte("X-Value: a\x00b\r\n\r\n"), strict)
+	require.ErrorIs(t, err, ErrInvalidHeaderValue)
+	headers = NewHeaders()
+	_, _, _, err = headers.ParseWithOptions([]byte("X-Value: a\tb\xe9\r\n\r\n"), strict)
+	require.NoError(t, err)
+}
+
+func TestHeaderParseLenient(t *testing.T) {
+	lenient := ParseOptions{Strict: true, Lenient: LenientBareLF | LenientObsFold}
+
+	// Test: Bare LF ends the line
+	headers := NewHeaders()
+	n, done, used, err := headers.ParseWithOptions([]byte("Host: localhost\nAccept: */*\n\n"), lenient)
+	require.NoError(t, err)
+	assert.Equal(t, 16, n)
+	assert.False(t, done)
+	assert.Equal(t, LenientBareLF, used)
+	assert.Equal(t, "localhost", headers.testGet("Host"))
+
+	// Test: Bare LF on the empty line ends the block
+	n, done, used, err = headers.ParseWithOptions([]byte("\n"), lenient)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.True(t, done)
+	assert.Equal(t, LenientBareLF, used)
+
+	// Test: CRLF needs no leniency
+	headers = NewHeaders()
+	_, _, used, err = headers.ParseWithOptions([]byte("Host: localhost\r\n\r\n"), lenient)
+	require.NoError(t, err)
+	assert.Zero(t, used)
+
+	// Test: Continuation lines are unfolded
+	headers = NewHeaders()
+	data := []byte("X-Folded: first\r\n  second\r\n\tthird\r\nHost: localhost\r\n\r\n")
+	n, done, used, err = headers.ParseWithOptions(data, lenient)
+	require.NoError(t, err)
+	assert.Equal(t, 35, n)
+	assert.False(t, done)
+	assert.Equal(t, LenientObsFold, used)
+	assert.Equal(t, "first second third", headers.testGet("X-Folded"))
+
+	// Test: Wait for the next line before deciding there is no continuation
+	headers = NewHeaders()
+	n, _, _, err = headers.ParseWithOptions([]byte("X-Folded: first\r\n"), lenient)
+	require.NoError(t, err)
+	assert.Equal(t, 0, n)
+
+	// Test: A field line cannot start with whitespace
+	headers = NewHeaders()
+	_, _, _, err = headers.ParseWithOptions([]byte(" Host: localhost\r\n\r\n"), lenient)
+	require.ErrorIs(t, err, ErrObsFold)
+
+	// Test: Bare CR is still rejected in strict mode
+	headers = NewHeaders()
+	_, _, _, err = headers.ParseWithOptions([]byte("Host: a\rb\n\n"), lenient)
+	require.ErrorIs(t, err, ErrBareLineEnding)
+}
+
+func TestHeadersFieldLines(t *testing.T) {
+	// Test: Parsed lines keep their order, casing and separate values
+	headers := NewHeaders()
+	data := []byte("Host: localhost\r\nSet-Cookie: a=1; Expires=Fri, 01 Mar 2024 12:30:00 GMT\r\nX-Trace: one\r\nset-cookie: b=2\r\n\r\n")
+	_, _, err := headers.loopHelper(data)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a=1; Expires=Fri, 01 Mar 2024 12:30:00 GMT", "b=2"}, headers.Values("Set-Cookie"))
+	assert.Equal(t, []string{"Host", "Set-Cookie", "X-Trace", "set-cookie"}, names(headers))
+	assert.Equal(t, 4, headers.Len())
+
+	// Test: Add appends, Set replaces in place, Del removes every line
+	headers.Add("X-Trace", "two")
+	assert.Equal(t, []string{"one", "two"}, headers.Values("x-trace"))
+	assert.Equal(t, "one, two", headers.testGet("X-TRACE"))
+	require.NoError(t, headers.Set("Set-Cookie", "c=3"))
+	assert.Equal(t, []string{"c=3"}, headers.Values("set-cookie"))
+	assert.Equal(t, []string{"Host", "Set-Cookie", "X-Trace", "X-Trace"}, names(headers))
+	require.NoError(t, headers.Set("Content-Type", "text/plain"))
+	assert.Equal(t, []string{"Host", "Set-Cookie", "X-Trace", "X-Trace", "Content-Type"}, names(headers))
+	headers.Del("x-trace")
+	assert.Equal(t, []string{"Host", "Set-Cookie", "Content-Type"}, names(headers))
+	assert.Nil(t, headers.Values("X-Trace"))
+	_, ok := headers.Get("X-Trace")
+	assert.False(t, ok)
+
+	// Test: Invalid names are rejected
+	require.ErrorIs(t, headers.Add("Bad Name", "x"), ErrInvalidHeaderName)
+	require.ErrorIs(t, headers.Set("", "x"), ErrInvalidHeaderName)
+
+	// Test: Clone is independent
+	clone := headers.Clone()
+	clone.Set("Host", "example.com")
+	assert.Equal(t, "localhost", headers.testGet("Host"))
+	assert.Equal(t, "example.com", clone.testGet("Host"))
+
+	// Test: A nil *Headers reads as empty
+	var empty *Headers
+	_, ok = empty.Get("Host")
+	assert.False(t, ok)
+	assert.Nil(t, empty.Values("Host"))
+	assert.Zero(t, empty.Len())
+	assert.Empty(t, names(empty))
+	empty.Del("Host")
+}
+
+func names(h *Headers) []string {
+	var names []string
+	for name := range h.All() {
+		names = append(names, name)
+	}
+	return names
+}
@@ -0,0 +1,14 @@
+// Package headerstest provides helpers for tests that build header sections.
+package headerstest
+
+import "httpfromtcp/internal/headers"
+
+// Fields builds headers from name, value pairs, adding each pair in order so
+// that repeated names become separate field lines.
+func Fields(pairs ...string) *headers.Headers {
+	h := headers.NewHeaders()
+	for i := 0; i+1 < len(pairs); i += 2 {
+		h.Add(pairs[i], pairs[i+1])
+	}
+	return h
+}
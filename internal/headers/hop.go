@@ -0,0 +1,49 @@
+package headers
+
+import "strings"
+
+// hopByHopFields only describe the connection they arrive on, so an
+// intermediary must not forward them. Proxy-Connection is not standard but
+// is still sent by some clients.
+var hopByHopFields = []string{
+	"Connection",
+	"Keep-Alive",
+	"Proxy-Connection",
+	"TE",
+	"Trailer",
+	"Transfer-Encoding",
+	"Upgrade",
+}
+
+// RemoveHopByHop deletes the fields a proxy must not forward, following RFC
+// 9110 section 7.6.1: every field named by a Connection option, the
+// hop-by-hop fields Connection, Keep-Alive, TE, Trailer, Transfer-Encoding
+// and Upgrade, and all Proxy-* fields. Framing then has to be set anew for
+// the next hop.
+func (h *Headers) RemoveHopByHop() {
+	if h == nil {
+		return
+	}
+	for _, option := range h.GetList("Connection") {
+		h.Del(option)
+	}
+	kept := h.lines[:0]
+	for _, line := range h.lines {
+		if !IsHopByHop(line.name) {
+			kept = append(kept, line)
+		}
+	}
+	clear(h.lines[len(kept):])
+	h.lines = kept
+}
+
+// IsHopByHop reports whether name is always hop-by-hop, whatever the
+// Connection field says.
+func IsHopByHop(name string) bool {
+	for _, field := range hopByHopFields {
+		if strings.EqualFold(field, name) {
+			return true
+		}
+	}
+	return len(name) > len("Proxy-") && strings.EqualFold(name[:len("Proxy-")], "Proxy-")
+}
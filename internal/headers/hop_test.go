@@ -0,0 +1,48 @@
+package headers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRemoveHopByHop(t *testing.T) {
+	h := NewHeaders()
+	h.Add("Host", "example.com")
+	h.Add("Connection", "keep-alive, X-Session")
+	h.Add("connection", "Upgrade, x-debug")
+	h.Add("Keep-Alive", "timeout=5")
+	h.Add("TE", "trailers")
+	h.Add("Trailer", "X-Checksum")
+	h.Add("Transfer-Encoding", "chunked")
+	h.Add("Upgrade", "websocket")
+	h.Add("Proxy-Authorization", "Basic Zm9vOmJhcg==")
+	h.Add("proxy-connection", "keep-alive")
+	h.Add("X-Session", "abc")
+	h.Add("Accept", "*/*")
+	h.Add("X-Debug", "1")
+	h.Add("X-Debug", "2")
+	h.Add("Content-Length", "10")
+	h.Add("Proxy", "not a Proxy-* field")
+
+	h.RemoveHopByHop()
+	assert.Equal(t, []string{"Host", "Accept", "Content-Length", "Proxy"}, names(h))
+
+	// Test: Nothing to remove
+	h = NewHeaders()
+	h.Add("Accept", "*/*")
+	h.RemoveHopByHop()
+	assert.Equal(t, []string{"Accept"}, names(h))
+
+	var empty *Headers
+	empty.RemoveHopByHop()
+}
+
+func TestIsHopByHop(t *testing.T) {
+	for _, name := range []string{"connection", "Keep-Alive", "te", "TRAILER", "Transfer-Encoding", "upgrade", "Proxy-Authenticate", "proxy-authorization"} {
+		assert.True(t, IsHopByHop(name), name)
+	}
+	for _, name := range []string{"Content-Length", "Trailers", "Proxy", "Proxy-", "Host"} {
+		assert.False(t, IsHopByHop(name), name)
+	}
+}
@@ -0,0 +1,143 @@
+package headers
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxRanges bounds how many ranges one Range header may ask for; more than
+// that is treated as an invalid header and the full content is served.
+const maxRanges = 100
+
+var (
+	ErrInvalidRange         = errors.New("invalid range")
+	ErrUnsatisfiableRange   = errors.New("range not satisfiable")
+	ErrUnsupportedRangeUnit = errors.New("unsupported range unit")
+)
+
+// RangeSpec is one byte-range-spec from a Range header. First is -1 for a
+// suffix range covering the last Last bytes, and Last is -1 for a range that
+// runs to the end.
+type RangeSpec struct {
+	First int64
+	Last  int64
+}
+
+// ByteRange is a RangeSpec resolved against a representation of known size.
+type ByteRange struct {
+	Start  int64
+	Length int64
+}
+
+// ContentRange formats r as a Content-Range value for content of size bytes.
+func (r ByteRange) ContentRange(size int64) string {
+	return fmt.Sprintf("bytes %d-%d/%d", r.Start, r.Start+r.Length-1, size)
+}
+
+// ParseRange parses a Range value such as "bytes=0-499,-500,1000-". Only the
+// bytes unit is understood.
+func ParseRange(value string) ([]RangeSpec, error) {
+	unit, set, ok := strings.Cut(strings.TrimSpace(value), "=")
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidRange, value)
+	}
+	if !strings.EqualFold(strings.TrimSpace(unit), "bytes") {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedRangeUnit, unit)
+	}
+	var specs []RangeSpec
+	for _, part := range strings.Split(set, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		first, last, ok := strings.Cut(part, "-")
+		if !ok {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidRange, part)
+		}
+		spec := RangeSpec{First: -1, Last: -1}
+		var err error
+		if first != "" {
+			if spec.First, err = parseRangeInt(first); err != nil {
+				return nil, fmt.Errorf("%w: %q", ErrInvalidRange, part)
+			}
+		}
+		if last != "" {
+			if spec.Last, err = parseRangeInt(last); err != nil {
+				return nil, fmt.Errorf("%w: %q", ErrInvalidRange, part)
+			}
+		}
+		if first == "" && last == "" || spec.First >= 0 && spec.Last >= 0 && spec.Last < spec.First {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidRange, part)
+		}
+		specs = append(specs, spec)
+		if len(specs) > maxRanges {
+			return nil, fmt.Errorf("%w: more than %d ranges", ErrInvalidRange, maxRanges)
+		}
+	}
+	if len(specs) == 0 {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidRange, value)
+	}
+	return specs, nil
+}
+
+func parseRangeInt(s string) (int64, error) {
+	if strings.TrimLeft(s, "0123456789") != "" {
+		return 0, strconv.ErrSyntax
+	}
+	return strconv.ParseInt(s, 10, 64)
+}
+
+// ResolveRanges turns specs into byte ranges within size bytes, dropping the
+// ones that start past the end. It fails with ErrUnsatisfiableRange if none
+// are left.
+func ResolveRanges(specs []RangeSpec, size int64) ([]ByteRange, error) {
+	var ranges []ByteRange
+	for _, spec := range specs {
+		var r ByteRange
+		switch {
+		case spec.First == -1:
+			if spec.Last == 0 || size == 0 {
+				continue
+			}
+			r.Start = max(size-spec.Last, 0)
+			r.Length = size - r.Start
+		default:
+			if spec.First >= size {
+				continue
+			}
+			r.Start = spec.First
+			end := size - 1
+			if spec.Last != -1 && spec.Last < end {
+				end = spec.Last
+			}
+			r.Length = end - r.Start + 1
+		}
+		ranges = append(ranges, r)
+	}
+	if len(ranges) == 0 {
+		return nil, fmt.Errorf("%w: no range within %d bytes", ErrUnsatisfiableRange, size)
+	}
+	return ranges, nil
+}
+
+// IfRangeMatches reports whether an If-Range value still matches the
+// representation described by etag and lastModified, so the Range header can
+// be honoured. An entity tag has to match strongly; a date has to equal
+// lastModified exactly.
+func IfRangeMatches(ifRange, etag string, lastModified time.Time) bool {
+	ifRange = strings.TrimSpace(ifRange)
+	if strings.HasPrefix(ifRange, `"`) || strings.HasPrefix(ifRange, "W/") {
+		return ETagsMatch(ifRange, etag, false)
+	}
+	if lastModified.IsZero() {
+		return false
+	}
+	t, err := ParseTime(ifRange)
+	if err != nil {
+		return false
+	}
+	return t.Equal(lastModified.Truncate(time.Second))
+}
@@ -0,0 +1,75 @@
+package headers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRange(t *testing.T) {
+	tests := []struct {
+		value string
+		specs []RangeSpec
+	}{
+		{"bytes=0-499", []RangeSpec{{0, 499}}},
+		{"bytes=500-", []RangeSpec{{500, -1}}},
+		{"bytes=-500", []RangeSpec{{-1, 500}}},
+		{"bytes=0-0, -1", []RangeSpec{{0, 0}, {-1, 1}}},
+		{"Bytes = 0-1,,2-3", []RangeSpec{{0, 1}, {2, 3}}},
+	}
+	for _, tc := range tests {
+		specs, err := ParseRange(tc.value)
+		require.NoError(t, err, tc.value)
+		assert.Equal(t, tc.specs, specs, tc.value)
+	}
+
+	for _, value := range []string{"", "bytes", "bytes=", "bytes=-", "bytes=5-1", "bytes=a-b", "bytes=+1-2", "bytes=1-2-3"} {
+		_, err := ParseRange(value)
+		require.ErrorIs(t, err, ErrInvalidRange, value)
+	}
+	_, err := ParseRange("items=0-1")
+	require.ErrorIs(t, err, ErrUnsupportedRangeUnit)
+
+	many := "bytes=0-0"
+	for i := 0; i < maxRanges; i++ {
+		many += ",0-0"
+	}
+	_, err = ParseRange(many)
+	require.ErrorIs(t, err, ErrInvalidRange)
+}
+
+func TestResolveRanges(t *testing.T) {
+	specs := []RangeSpec{{0, 99}, {900, -1}, {-1, 50}, {950, 2000}, {1000, -1}}
+	ranges, err := ResolveRanges(specs, 1000)
+	require.NoError(t, err)
+	assert.Equal(t, []ByteRange{{0, 100}, {900, 100}, {950, 50}, {950, 50}}, ranges)
+	assert.Equal(t, "bytes 0-99/1000", ranges[0].ContentRange(1000))
+
+	// Test: A suffix longer than the content is the whole content
+	ranges, err = ResolveRanges([]RangeSpec{{-1, 5000}}, 1000)
+	require.NoError(t, err)
+	assert.Equal(t, []ByteRange{{0, 1000}}, ranges)
+
+	// Test: Nothing satisfiable
+	_, err = ResolveRanges([]RangeSpec{{1000, -1}, {-1, 0}}, 1000)
+	require.ErrorIs(t, err, ErrUnsatisfiableRange)
+	_, err = ResolveRanges([]RangeSpec{{-1, 10}}, 0)
+	require.ErrorIs(t, err, ErrUnsatisfiableRange)
+}
+
+func TestIfRangeMatches(t *testing.T) {
+	modified := time.Date(2024, 3, 1, 12, 30, 0, 500, time.UTC)
+
+	assert.True(t, IfRangeMatches(`"abc"`, `"abc"`, modified))
+	assert.False(t, IfRangeMatches(`"abc"`, `"def"`, modified))
+	assert.False(t, IfRangeMatches(`W/"abc"`, `W/"abc"`, modified))
+	assert.False(t, IfRangeMatches(`"abc"`, `W/"abc"`, modified))
+	assert.False(t, IfRangeMatches(`"abc"`, "", modified))
+
+	assert.True(t, IfRangeMatches("Fri, 01 Mar 2024 12:30:00 GMT", `"abc"`, modified))
+	assert.False(t, IfRangeMatches("Fri, 01 Mar 2024 12:29:59 GMT", `"abc"`, modified))
+	assert.False(t, IfRangeMatches("Fri, 01 Mar 2024 12:30:00 GMT", `"abc"`, time.Time{}))
+	assert.False(t, IfRangeMatches("yesterday", `"abc"`, modified))
+}
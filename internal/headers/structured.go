@@ -0,0 +1,642 @@
+package headers
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Structured Field Values, RFC 8941. Bare items are represented as int64
+// (Integer), float64 (Decimal), string (String), Token, []byte (Byte
+// Sequence) and bool (Boolean).
+
+var ErrInvalidStructuredField = errors.New("invalid structured field")
+
+// Token is a bare item written without quotes, such as the u in "u=1, i".
+type Token string
+
+// Param is one parameter of an item or inner list.
+type Param struct {
+	Key   string
+	Value any
+}
+
+// Params are parameters in the order they appeared.
+type Params []Param
+
+// Get returns the value of the parameter named key.
+func (p Params) Get(key string) (any, bool) {
+	for _, param := range p {
+		if param.Key == key {
+			return param.Value, true
+		}
+	}
+	return nil, false
+}
+
+// set overwrites an existing parameter in place or appends a new one.
+func (p Params) set(key string, value any) Params {
+	for i := range p {
+		if p[i].Key == key {
+			p[i].Value = value
+			return p
+		}
+	}
+	return append(p, Param{key, value})
+}
+
+// Member is an element of a List or a Dictionary value: an Item or an
+// InnerList.
+type Member interface {
+	member()
+}
+
+// Item is a bare item with parameters.
+type Item struct {
+	Value  any
+	Params Params
+}
+
+// InnerList is a parenthesized list of items with parameters of its own.
+type InnerList struct {
+	Items  []Item
+	Params Params
+}
+
+func (Item) member()      {}
+func (InnerList) member() {}
+
+// List is a List field such as Cache-Status.
+type List []Member
+
+// DictMember is one key and its value in a Dictionary.
+type DictMember struct {
+	Key   string
+	Value Member
+}
+
+// Dictionary is a Dictionary field such as Priority, in the order the keys
+// first appeared.
+type Dictionary []DictMember
+
+// Get returns the value of key.
+func (d Dictionary) Get(key string) (Member, bool) {
+	for _, m := range d {
+		if m.Key == key {
+			return m.Value, true
+		}
+	}
+	return nil, false
+}
+
+// ParseItem parses a field value as an Item.
+func ParseItem(value string) (Item, error) {
+	p := sfParser{s: value}
+	p.skipSP()
+	item, err := p.item()
+	if err != nil {
+		return Item{}, err
+	}
+	return item, p.end()
+}
+
+// ParseList parses a field value as a List. Field lines have to be joined
+// with commas first, as Headers.Get does.
+func ParseList(value string) (List, error) {
+	p := sfParser{s: value}
+	p.skipSP()
+	var list List
+	for !p.done() {
+		member, err := p.member()
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, member)
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+	}
+	return list, p.end()
+}
+
+// ParseDictionary parses a field value as a Dictionary. A repeated key keeps
+// its first position and takes the last value.
+func ParseDictionary(value string) (Dictionary, error) {
+	p := sfParser{s: value}
+	p.skipSP()
+	var dict Dictionary
+	for !p.done() {
+		key, err := p.key()
+		if err != nil {
+			return nil, err
+		}
+		var member Member
+		if p.peek() == '=' {
+			p.pos++
+			if member, err = p.member(); err != nil {
+				return nil, err
+			}
+		} else {
+			params, err := p.params()
+			if err != nil {
+				return nil, err
+			}
+			member = Item{Value: true, Params: params}
+		}
+		dict = dict.set(key, member)
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+	}
+	return dict, p.end()
+}
+
+func (d Dictionary) set(key string, value Member) Dictionary {
+	for i := range d {
+		if d[i].Key == key {
+			d[i].Value = value
+			return d
+		}
+	}
+	return append(d, DictMember{key, value})
+}
+
+type sfParser struct {
+	s   string
+	pos int
+}
+
+func (p *sfParser) done() bool {
+	return p.pos >= len(p.s)
+}
+
+func (p *sfParser) peek() byte {
+	if p.done() {
+		return 0
+	}
+	return p.s[p.pos]
+}
+
+func (p *sfParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("%w: %s at offset %d", ErrInvalidStructuredField, fmt.Sprintf(format, args...), p.pos)
+}
+
+func (p *sfParser) skipSP() {
+	for p.peek() == ' ' {
+		p.pos++
+	}
+}
+
+func (p *sfParser) skipOWS() {
+	for c := p.peek(); c == ' ' || c == '\t'; c = p.peek() {
+		p.pos++
+	}
+}
+
+// end checks that nothing but spaces follows the parsed value.
+func (p *sfParser) end() error {
+	p.skipSP()
+	if !p.done() {
+		return p.errorf("trailing characters")
+	}
+	return nil
+}
+
+// next moves past the comma between two members of a List or Dictionary.
+func (p *sfParser) next() error {
+	p.skipOWS()
+	if p.done() {
+		return nil
+	}
+	if p.peek() != ',' {
+		return p.errorf("expected comma")
+	}
+	p.pos++
+	p.skipOWS()
+	if p.done() {
+		return p.errorf("trailing comma")
+	}
+	return nil
+}
+
+func (p *sfParser) member() (Member, error) {
+	if p.peek() == '(' {
+		return p.innerList()
+	}
+	return p.item()
+}
+
+func (p *sfParser) innerList() (InnerList, error) {
+	p.pos++
+	var list InnerList
+	for !p.done() {
+		p.skipSP()
+		if p.peek() == ')' {
+			p.pos++
+			params, err := p.params()
+			if err != nil {
+				return InnerList{}, err
+			}
+			list.Params = params
+			return list, nil
+		}
+		item, err := p.item()
+		if err != nil {
+			return InnerList{}, err
+		}
+		list.Items = append(list.Items, item)
+		if c := p.peek(); c != ' ' && c != ')' {
+			return InnerList{}, p.errorf("expected space or ) in inner list")
+		}
+	}
+	return InnerList{}, p.errorf("unterminated inner list")
+}
+
+func (p *sfParser) item() (Item, error) {
+	value, err := p.bareItem()
+	if err != nil {
+		return Item{}, err
+	}
+	params, err := p.params()
+	if err != nil {
+		return Item{}, err
+	}
+	return Item{Value: value, Params: params}, nil
+}
+
+func (p *sfParser) params() (Params, error) {
+	var params Params
+	for p.peek() == ';' {
+		p.pos++
+		p.skipSP()
+		key, err := p.key()
+		if err != nil {
+			return nil, err
+		}
+		var value any = true
+		if p.peek() == '=' {
+			p.pos++
+			if value, err = p.bareItem(); err != nil {
+				return nil, err
+			}
+		}
+		params = params.set(key, value)
+	}
+	return params, nil
+}
+
+func (p *sfParser) key() (string, error) {
+	if c := p.peek(); !isLCAlpha(c) && c != '*' {
+		return "", p.errorf("invalid key")
+	}
+	start := p.pos
+	for !p.done() && isKeyChar(p.s[p.pos]) {
+		p.pos++
+	}
+	return p.s[start:p.pos], nil
+}
+
+func (p *sfParser) bareItem() (any, error) {
+	switch c := p.peek(); {
+	case c == '-' || isDigit(c):
+		return p.number()
+	case c == '"':
+		return p.string()
+	case c == ':':
+		return p.byteSequence()
+	case c == '?':
+		return p.boolean()
+	case c == '*' || isAlpha(c):
+		return p.token(), nil
+	}
+	return nil, p.errorf("invalid bare item")
+}
+
+func (p *sfParser) number() (any, error) {
+	start := p.pos
+	if p.peek() == '-' {
+		p.pos++
+	}
+	if !isDigit(p.peek()) {
+		return nil, p.errorf("number without digits")
+	}
+	digitsStart, dot := p.pos, -1
+	for !p.done() {
+		c := p.s[p.pos]
+		if c == '.' && dot == -1 {
+			if p.pos-digitsStart > 12 {
+				return nil, p.errorf("decimal integer part too long")
+			}
+			dot = p.pos
+		} else if !isDigit(c) {
+			break
+		}
+		p.pos++
+		if dot == -1 && p.pos-digitsStart > 15 {
+			return nil, p.errorf("integer too long")
+		}
+		if dot != -1 && p.pos-digitsStart > 16 {
+			return nil, p.errorf("decimal too long")
+		}
+	}
+	text := p.s[start:p.pos]
+	if dot == -1 {
+		n, err := strconv.ParseInt(text, 10, 64)
+		if err != nil {
+			return nil, p.errorf("invalid integer")
+		}
+		return n, nil
+	}
+	if fraction := p.pos - dot - 1; fraction == 0 || fraction > 3 {
+		return nil, p.errorf("decimal needs 1 to 3 fractional digits")
+	}
+	f, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return nil, p.errorf("invalid decimal")
+	}
+	return f, nil
+}
+
+func (p *sfParser) string() (string, error) {
+	p.pos++
+	var b strings.Builder
+	for !p.done() {
+		c := p.s[p.pos]
+		p.pos++
+		switch {
+		case c == '\\':
+			if c = p.peek(); c != '"' && c != '\\' {
+				return "", p.errorf("invalid escape in string")
+			}
+			p.pos++
+			b.WriteByte(c)
+		case c == '"':
+			return b.String(), nil
+		case c < 0x20 || c > 0x7e:
+			return "", p.errorf("invalid character in string")
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", p.errorf("unterminated string")
+}
+
+func (p *sfParser) token() Token {
+	start := p.pos
+	p.pos++
+	for !p.done() {
+		c := p.s[p.pos]
+		if !isTokenTable[c] && c != ':' && c != '/' {
+			break
+		}
+		p.pos++
+	}
+	return Token(p.s[start:p.pos])
+}
+
+func (p *sfParser) byteSequence() ([]byte, error) {
+	p.pos++
+	end := strings.IndexByte(p.s[p.pos:], ':')
+	if end == -1 {
+		return nil, p.errorf("unterminated byte sequence")
+	}
+	text := p.s[p.pos : p.pos+end]
+	p.pos += end + 1
+	for i := 0; i < len(text); i++ {
+		if c := text[i]; !isAlpha(c) && !isDigit(c) && c != '+' && c != '/' && c != '=' {
+			return nil, p.errorf("invalid character in byte sequence")
+		}
+	}
+	b, err := base64.StdEncoding.DecodeString(text)
+	if err != nil {
+		// Padding is optional for parsers.
+		if b, err = base64.RawStdEncoding.DecodeString(strings.TrimRight(text, "=")); err != nil {
+			return nil, p.errorf("invalid base64 in byte sequence")
+		}
+	}
+	return b, nil
+}
+
+func (p *sfParser) boolean() (bool, error) {
+	p.pos++
+	switch p.peek() {
+	case '1':
+		p.pos++
+		return true, nil
+	case '0':
+		p.pos++
+		return false, nil
+	}
+	return false, p.errorf("invalid boolean")
+}
+
+// SerializeItem formats an Item as a field value.
+func SerializeItem(item Item) (string, error) {
+	var b strings.Builder
+	if err := writeItem(&b, item); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// SerializeList formats a List as a field value. An empty list gives an
+// empty string; the field should then not be sent at all.
+func SerializeList(list List) (string, error) {
+	var b strings.Builder
+	for i, member := range list {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		if err := writeMember(&b, member); err != nil {
+			return "", err
+		}
+	}
+	return b.String(), nil
+}
+
+// SerializeDictionary formats a Dictionary as a field value. Members whose
+// value is true are written as their key alone.
+func SerializeDictionary(dict Dictionary) (string, error) {
+	var b strings.Builder
+	for i, m := range dict {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		if err := writeKey(&b, m.Key); err != nil {
+			return "", err
+		}
+		if item, ok := m.Value.(Item); ok && item.Value == true {
+			if err := writeParams(&b, item.Params); err != nil {
+				return "", err
+			}
+			continue
+		}
+		b.WriteByte('=')
+		if err := writeMember(&b, m.Value); err != nil {
+			return "", err
+		}
+	}
+	return b.String(), nil
+}
+
+func writeMember(b *strings.Builder, member Member) error {
+	switch m := member.(type) {
+	case Item:
+		return writeItem(b, m)
+	case InnerList:
+		b.WriteByte('(')
+		for i, item := range m.Items {
+			if i > 0 {
+				b.WriteByte(' ')
+			}
+			if err := writeItem(b, item); err != nil {
+				return err
+			}
+		}
+		b.WriteByte(')')
+		return writeParams(b, m.Params)
+	}
+	return fmt.Errorf("%w: member of type %T", ErrInvalidStructuredField, member)
+}
+
+func writeItem(b *strings.Builder, item Item) error {
+	if err := writeBareItem(b, item.Value); err != nil {
+		return err
+	}
+	return writeParams(b, item.Params)
+}
+
+func writeParams(b *strings.Builder, params Params) error {
+	for _, param := range params {
+		b.WriteByte(';')
+		if err := writeKey(b, param.Key); err != nil {
+			return err
+		}
+		if param.Value == true {
+			continue
+		}
+		b.WriteByte('=')
+		if err := writeBareItem(b, param.Value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeKey(b *strings.Builder, key string) error {
+	if key == "" || !isLCAlpha(key[0]) && key[0] != '*' {
+		return fmt.Errorf("%w: key %q", ErrInvalidStructuredField, key)
+	}
+	for i := 1; i < len(key); i++ {
+		if !isKeyChar(key[i]) {
+			return fmt.Errorf("%w: key %q", ErrInvalidStructuredField, key)
+		}
+	}
+	b.WriteString(key)
+	return nil
+}
+
+func writeBareItem(b *strings.Builder, value any) error {
+	switch v := value.(type) {
+	case int64:
+		if v < -999_999_999_999_999 || v > 999_999_999_999_999 {
+			return fmt.Errorf("%w: integer %d out of range", ErrInvalidStructuredField, v)
+		}
+		b.WriteString(strconv.FormatInt(v, 10))
+	case int:
+		return writeBareItem(b, int64(v))
+	case float64:
+		return writeDecimal(b, v)
+	case string:
+		b.WriteByte('"')
+		for i := 0; i < len(v); i++ {
+			c := v[i]
+			if c < 0x20 || c > 0x7e {
+				return fmt.Errorf("%w: invalid character in string", ErrInvalidStructuredField)
+			}
+			if c == '"' || c == '\\' {
+				b.WriteByte('\\')
+			}
+			b.WriteByte(c)
+		}
+		b.WriteByte('"')
+	case Token:
+		if v == "" || !isAlpha(v[0]) && v[0] != '*' {
+			return fmt.Errorf("%w: token %q", ErrInvalidStructuredField, v)
+		}
+		for i := 1; i < len(v); i++ {
+			if c := v[i]; !isTokenTable[c] && c != ':' && c != '/' {
+				return fmt.Errorf("%w: token %q", ErrInvalidStructuredField, v)
+			}
+		}
+		b.WriteString(string(v))
+	case []byte:
+		b.WriteByte(':')
+		b.WriteString(base64.StdEncoding.EncodeToString(v))
+		b.WriteByte(':')
+	case bool:
+		if v {
+			b.WriteString("?1")
+		} else {
+			b.WriteString("?0")
+		}
+	default:
+		return fmt.Errorf("%w: bare item of type %T", ErrInvalidStructuredField, value)
+	}
+	return nil
+}
+
+// writeDecimal rounds v to three fractional digits, ties to even, and
+// writes it with as few fractional digits as it needs, but at least one.
+// Rounding works on the shortest decimal form of v, so 0.0005 is a tie.
+func writeDecimal(b *strings.Builder, v float64) error {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return fmt.Errorf("%w: decimal %v", ErrInvalidStructuredField, v)
+	}
+	intPart, frac, _ := strings.Cut(strconv.FormatFloat(math.Abs(v), 'f', -1, 64), ".")
+	if len(intPart) > 12 {
+		return fmt.Errorf("%w: decimal %v out of range", ErrInvalidStructuredField, v)
+	}
+	kept, rest := frac, ""
+	if len(frac) > 3 {
+		kept, rest = frac[:3], frac[3:]
+	}
+	kept += strings.Repeat("0", 3-len(kept))
+	n, _ := strconv.ParseInt(intPart+kept, 10, 64)
+	if rest != "" && (rest[0] > '5' || rest[0] == '5' && (strings.Trim(rest[1:], "0") != "" || n%2 == 1)) {
+		n++
+	}
+	if n >= 1e15 {
+		return fmt.Errorf("%w: decimal %v out of range", ErrInvalidStructuredField, v)
+	}
+	if v < 0 && n != 0 {
+		b.WriteByte('-')
+	}
+	fraction := strings.TrimRight(fmt.Sprintf("%03d", n%1000), "0")
+	if fraction == "" {
+		fraction = "0"
+	}
+	b.WriteString(strconv.FormatInt(n/1000, 10))
+	b.WriteByte('.')
+	b.WriteString(fraction)
+	return nil
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+func isLCAlpha(c byte) bool {
+	return 'a' <= c && c <= 'z'
+}
+
+func isAlpha(c byte) bool {
+	return isLCAlpha(c) || 'A' <= c && c <= 'Z'
+}
+
+func isKeyChar(c byte) bool {
+	return isLCAlpha(c) || isDigit(c) || c == '_' || c == '-' || c == '.' || c == '*'
+}
@@ -0,0 +1,329 @@
+package headers
+
+import (
+	"encoding/base32"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sfTest is one case in the JSON format of the httpwg structured-field-tests
+// suite.
+type sfTest struct {
+	Name       string          `json:"name"`
+	Raw        []string        `json:"raw"`
+	HeaderType string          `json:"header_type"`
+	Expected   json.RawMessage `json:"expected"`
+	MustFail   bool            `json:"must_fail"`
+	CanFail    bool            `json:"can_fail"`
+	Canonical  []string        `json:"canonical"`
+}
+
+func loadSFTests(t *testing.T, pattern string) map[string][]sfTest {
+	files, err := filepath.Glob(filepath.Join("testdata", "structured-field-tests", pattern))
+	require.NoError(t, err)
+	require.NotEmpty(t, files)
+	suites := map[string][]sfTest{}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		require.NoError(t, err)
+		var tests []sfTest
+		require.NoError(t, json.Unmarshal(data, &tests), file)
+		suites[filepath.Base(file)] = tests
+	}
+	return suites
+}
+
+func TestStructuredFieldParse(t *testing.T) {
+	for file, tests := range loadSFTests(t, "*.json") {
+		for _, tc := range tests {
+			t.Run(file+"/"+tc.Name, func(t *testing.T) {
+				raw := strings.Join(tc.Raw, ", ")
+				got, err := parseSF(tc.HeaderType, raw)
+				if tc.MustFail {
+					require.ErrorIs(t, err, ErrInvalidStructuredField)
+					return
+				}
+				if err != nil && tc.CanFail {
+					return
+				}
+				require.NoError(t, err)
+				assert.Equal(t, sfFromJSON(t, tc.HeaderType, tc.Expected), got)
+
+				serialized, err := serializeSF(got)
+				require.NoError(t, err)
+				want := raw
+				if tc.Canonical != nil {
+					want = strings.Join(tc.Canonical, ", ")
+				}
+				assert.Equal(t, want, serialized)
+			})
+		}
+	}
+}
+
+func TestStructuredFieldSerialize(t *testing.T) {
+	for file, tests := range loadSFTests(t, "serialisation-tests/*.json") {
+		for _, tc := range tests {
+			t.Run(file+"/"+tc.Name, func(t *testing.T) {
+				serialized, err := serializeSF(sfFromJSON(t, tc.HeaderType, tc.Expected))
+				if tc.MustFail {
+					require.ErrorIs(t, err, ErrInvalidStructuredField)
+					return
+				}
+				require.NoError(t, err)
+				assert.Equal(t, strings.Join(tc.Canonical, ", "), serialized)
+			})
+		}
+	}
+}
+
+func parseSF(headerType, raw string) (any, error) {
+	switch headerType {
+	case "item":
+		return ParseItem(raw)
+	case "list":
+		return ParseList(raw)
+	}
+	return ParseDictionary(raw)
+}
+
+func serializeSF(value any) (string, error) {
+	switch v := value.(type) {
+	case Item:
+		return SerializeItem(v)
+	case List:
+		return SerializeList(v)
+	}
+	return SerializeDictionary(value.(Dictionary))
+}
+
+// sfFromJSON builds the value a test case expects from its JSON form.
+func sfFromJSON(t *testing.T, headerType string, data json.RawMessage) any {
+	dec := json.NewDecoder(strings.NewReader(string(data)))
+	dec.UseNumber()
+	var v any
+	require.NoError(t, dec.Decode(&v))
+	switch headerType {
+	case "item":
+		return sfItem(t, v)
+	case "list":
+		var list List
+		for _, member := range v.([]any) {
+			list = append(list, sfMember(t, member))
+		}
+		return list
+	}
+	var dict Dictionary
+	for _, member := range v.([]any) {
+		pair := member.([]any)
+		dict = append(dict, DictMember{Key: pair[0].(string), Value: sfMember(t, pair[1])})
+	}
+	return dict
+}
+
+func sfMember(t *testing.T, v any) Member {
+	pair := v.([]any)
+	if items, ok := pair[0].([]any); ok {
+		list := InnerList{Params: sfParams(t, pair[1])}
+		for _, item := range items {
+			list.Items = append(list.Items, sfItem(t, item))
+		}
+		return list
+	}
+	return sfItem(t, v)
+}
+
+func sfItem(t *testing.T, v any) Item {
+	pair := v.([]any)
+	return Item{Value: sfBareItem(t, pair[0]), Params: sfParams(t, pair[1])}
+}
+
+func sfParams(t *testing.T, v any) Params {
+	var params Params
+	for _, param := range v.([]any) {
+		pair := param.([]any)
+		params = append(params, Param{Key: pair[0].(string), Value: sfBareItem(t, pair[1])})
+	}
+	return params
+}
+
+func sfBareItem(t *testing.T, v any) any {
+	switch v := v.(type) {
+	case json.Number:
+		if strings.ContainsAny(string(v), ".eE") {
+			f, err := strconv.ParseFloat(string(v), 64)
+			require.NoError(t, err)
+			return f
+		}
+		n, err := strconv.ParseInt(string(v), 10, 64)
+		require.NoError(t, err)
+		return n
+	case map[string]any:
+		switch v["__type"] {
+		case "token":
+			return Token(v["value"].(string))
+		case "binary":
+			b, err := base32.StdEncoding.DecodeString(v["value"].(string))
+			require.NoError(t, err)
+			return b
+		}
+		t.Fatalf("unknown type %v", v["__type"])
+	}
+	return v
+}
+
+// TestStructuredFieldGenerated covers every ASCII byte in keys, tokens and
+// strings, every length of number and large values, in the way of the
+// generated files of the httpwg suite.
+func TestStructuredFieldGenerated(t *testing.T) {
+	isLCAlpha := func(c byte) bool { return 'a' <= c && c <= 'z' }
+	isAlpha := func(c byte) bool { return isLCAlpha(c) || 'A' <= c && c <= 'Z' }
+	isDigit := func(c byte) bool { return '0' <= c && c <= '9' }
+	isTChar := func(c byte) bool {
+		return isAlpha(c) || isDigit(c) || strings.IndexByte("!#$%&'*+-.^_`|~", c) >= 0
+	}
+
+	for i := 0; i < 128; i++ {
+		c := byte(i)
+
+		// Test: Keys start with lcalpha or "*" and go on with lcalpha,
+		// DIGIT, "_", "-", "." or "*". A space, comma or semicolon would
+		// start another valid value, so those are left out.
+		if c != ' ' && c != ',' && c != ';' {
+			_, err := ParseDictionary(string(c) + "a=1")
+			assert.Equal(t, isLCAlpha(c) || c == '*', err == nil, "0x%02x starting a dictionary key", c)
+			_, err = ParseDictionary("a" + string(c) + "a=1")
+			assert.Equal(t, isLCAlpha(c) || isDigit(c) || strings.IndexByte("_-.*", c) >= 0, err == nil,
+				"0x%02x in a dictionary key", c)
+			_, err = ParseItem("1;a" + string(c) + "a=1")
+			assert.Equal(t, isLCAlpha(c) || isDigit(c) || strings.IndexByte("_-.*", c) >= 0, err == nil,
+				"0x%02x in a parameter key", c)
+		}
+
+		// Test: Tokens start with ALPHA or "*" and go on with tchar, ":"
+		// or "/"
+		if c != ' ' {
+			item, err := ParseItem(string(c) + "a")
+			valid := isAlpha(c) || c == '*'
+			require.Equal(t, valid, err == nil, "0x%02x starting a token", c)
+			if valid {
+				assert.Equal(t, Token(string(c)+"a"), item.Value)
+			}
+		}
+		if c != ';' {
+			item, err := ParseItem("a" + string(c) + "a")
+			valid := isTChar(c) || c == ':' || c == '/'
+			require.Equal(t, valid, err == nil, "0x%02x in a token", c)
+			if valid {
+				serialized, err := SerializeItem(item)
+				require.NoError(t, err)
+				assert.Equal(t, "a"+string(c)+"a", serialized)
+			}
+		}
+
+		// Test: Strings hold printable ASCII, with only DQUOTE and "\"
+		// escaped
+		item, err := ParseItem(`"` + string(c) + `"`)
+		valid := 0x20 <= c && c <= 0x7e && c != '"' && c != '\\'
+		require.Equal(t, valid, err == nil, "0x%02x in a string", c)
+		if valid {
+			assert.Equal(t, string(c), item.Value)
+		}
+		item, err = ParseItem(`"\` + string(c) + `"`)
+		valid = c == '"' || c == '\\'
+		require.Equal(t, valid, err == nil, "escaped 0x%02x in a string", c)
+		if valid {
+			assert.Equal(t, string(c), item.Value)
+		}
+		_, err = SerializeItem(Item{Value: string(c)})
+		assert.Equal(t, 0x20 <= c && c <= 0x7e, err == nil, "serializing 0x%02x in a string", c)
+	}
+
+	// Test: Integers have up to 15 digits, Decimals up to 12 integer and 3
+	// fractional digits
+	for digits := 1; digits <= 16; digits++ {
+		for _, sign := range []string{"", "-"} {
+			raw := sign + strings.Repeat("1", digits)
+			item, err := ParseItem(raw)
+			require.Equal(t, digits <= 15, err == nil, raw)
+			if err == nil {
+				serialized, err := SerializeItem(item)
+				require.NoError(t, err)
+				assert.Equal(t, raw, serialized)
+			}
+		}
+	}
+	for intDigits := 1; intDigits <= 13; intDigits++ {
+		for fracDigits := 1; fracDigits <= 4; fracDigits++ {
+			raw := strings.Repeat("1", intDigits) + "." + strings.Repeat("1", fracDigits)
+			item, err := ParseItem(raw)
+			require.Equal(t, intDigits <= 12 && fracDigits <= 3, err == nil, raw)
+			if err == nil {
+				serialized, err := SerializeItem(item)
+				require.NoError(t, err)
+				assert.Equal(t, raw, serialized)
+			}
+		}
+	}
+
+	// Test: Large values parse and serialize back unchanged
+	members := make([]string, 1024)
+	for i := range members {
+		members[i] = "a" + strconv.Itoa(i)
+	}
+	large := map[string]string{
+		"list":       strings.Join(members, ", "),
+		"dictionary": strings.Join(members, "=1, ") + "=1",
+		"inner list": "(" + strings.Join(members[:256], " ") + ")",
+		"params":     "a;" + strings.Join(members[:256], ";"),
+		"key":        strings.Repeat("a", 100) + "=1",
+		"string":     `"` + strings.Repeat("a", 1024) + `"`,
+		"token":      strings.Repeat("a", 512),
+	}
+	for name, raw := range large {
+		headerType := "list"
+		switch name {
+		case "dictionary", "key":
+			headerType = "dictionary"
+		case "params", "string", "token":
+			headerType = "item"
+		}
+		value, err := parseSF(headerType, raw)
+		require.NoError(t, err, name)
+		serialized, err := serializeSF(value)
+		require.NoError(t, err, name)
+		assert.Equal(t, raw, serialized, name)
+	}
+}
+
+func TestStructuredFieldAccessors(t *testing.T) {
+	dict, err := ParseDictionary("u=1, i")
+	require.NoError(t, err)
+	u, ok := dict.Get("u")
+	require.True(t, ok)
+	assert.Equal(t, int64(1), u.(Item).Value)
+	_, ok = dict.Get("x")
+	assert.False(t, ok)
+
+	item, err := ParseItem(`text/html;q=0.5;level`)
+	require.NoError(t, err)
+	q, ok := item.Params.Get("q")
+	require.True(t, ok)
+	assert.Equal(t, 0.5, q)
+	level, _ := item.Params.Get("level")
+	assert.Equal(t, true, level)
+
+	// Test: Go ints serialize as Integers, other types are rejected
+	s, err := SerializeItem(Item{Value: 7})
+	require.NoError(t, err)
+	assert.Equal(t, "7", s)
+	_, err = SerializeItem(Item{Value: uint8(7)})
+	require.ErrorIs(t, err, ErrInvalidStructuredField)
+}
@@ -4,8 +4,26 @@ func isValidKey(key string) bool {
 	if len(key) == 0 {
 		return false
 	}
-	for _, char := range key {
-		if !isTokenTable[char] {
+	for i := 0; i < len(key); i++ {
+		if !isTokenTable[key[i]] {
+			return false
+		}
+	}
+	return true
+}
+
+// IsToken reports whether s is a non-empty RFC 9110 token, the syntax of
+// field names and methods.
+func IsToken(s string) bool {
+	return isValidKey(s)
+}
+
+// isValidValue reports whether value holds only visible characters, spaces,
+// tabs and obs-text, as field-value allows.
+func isValidValue(value string) bool {
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		if c < ' ' && c != '\t' || c == 0x7f {
 			return false
 		}
 	}
@@ -0,0 +1,177 @@
+package headers
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrMissingField     = errors.New("missing header field")
+	ErrInvalidNumber    = errors.New("invalid number")
+	ErrInvalidMediaType = errors.New("invalid media type")
+)
+
+// MediaType is a parsed media-type such as "text/html; charset=utf-8". Type,
+// Subtype and parameter names are lowercase; parameter values are unquoted
+// but otherwise kept as sent.
+type MediaType struct {
+	Type    string
+	Subtype string
+	Params  map[string]string
+}
+
+// GetInt64 returns the named field as a non-negative decimal integer. It
+// fails with ErrMissingField if the field is absent and ErrInvalidNumber if
+// it is not a single number or does not fit in an int64.
+func (h *Headers) GetInt64(name string) (int64, error) {
+	value, ok := h.Get(name)
+	if !ok {
+		return 0, fmt.Errorf("%w: %s", ErrMissingField, name)
+	}
+	n, err := ParseInt64(value)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", name, err)
+	}
+	return n, nil
+}
+
+// ParseInt64 parses 1*DIGIT, the syntax of numeric fields such as
+// Content-Length. Signs, spaces and values past the int64 range are
+// rejected.
+func ParseInt64(value string) (int64, error) {
+	if value == "" || strings.TrimLeft(value, "0123456789") != "" {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidNumber, value)
+	}
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %q out of range", ErrInvalidNumber, value)
+	}
+	return n, nil
+}
+
+// GetTime returns the named field as an HTTP-date in any of the formats
+// ParseTime accepts.
+func (h *Headers) GetTime(name string) (time.Time, error) {
+	value, ok := h.Get(name)
+	if !ok {
+		return time.Time{}, fmt.Errorf("%w: %s", ErrMissingField, name)
+	}
+	return ParseTime(value)
+}
+
+// SetTime sets the named field to t as an IMF-fixdate.
+func (h *Headers) SetTime(name string, t time.Time) error {
+	return h.Set(name, t.UTC().Format(TimeFormat))
+}
+
+// GetList returns the elements of a comma-separated list field, taken from
+// all of its lines in order. Commas inside quoted strings do not split, and
+// empty elements are dropped.
+func (h *Headers) GetList(name string) []string {
+	var elements []string
+	for _, value := range h.Values(name) {
+		for _, element := range SplitQuoted(value, ',') {
+			if element = strings.TrimSpace(element); element != "" {
+				elements = append(elements, element)
+			}
+		}
+	}
+	return elements
+}
+
+// GetMediaType parses the named field, normally Content-Type, as a media
+// type.
+func (h *Headers) GetMediaType(name string) (MediaType, error) {
+	value, ok := h.Get(name)
+	if !ok {
+		return MediaType{}, fmt.Errorf("%w: %s", ErrMissingField, name)
+	}
+	return ParseMediaType(value)
+}
+
+// ParseMediaType parses type "/" subtype followed by ";"-separated
+// parameters whose values are tokens or quoted strings.
+func ParseMediaType(value string) (MediaType, error) {
+	parts := SplitQuoted(value, ';')
+	typ, subtype, ok := strings.Cut(strings.TrimSpace(parts[0]), "/")
+	if !ok || !isValidKey(typ) || !isValidKey(subtype) {
+		return MediaType{}, fmt.Errorf("%w: %q", ErrInvalidMediaType, value)
+	}
+	m := MediaType{Type: strings.ToLower(typ), Subtype: strings.ToLower(subtype)}
+	for _, param := range parts[1:] {
+		param = strings.TrimSpace(param)
+		if param == "" {
+			continue
+		}
+		name, val, ok := strings.Cut(param, "=")
+		name = strings.ToLower(name)
+		if !ok || !isValidKey(name) {
+			return MediaType{}, fmt.Errorf("%w: parameter %q", ErrInvalidMediaType, param)
+		}
+		if strings.HasPrefix(val, `"`) {
+			if val, ok = unquote(val); !ok {
+				return MediaType{}, fmt.Errorf("%w: parameter %q", ErrInvalidMediaType, param)
+			}
+		} else if !isValidKey(val) {
+			return MediaType{}, fmt.Errorf("%w: parameter %q", ErrInvalidMediaType, param)
+		}
+		if _, dup := m.Params[name]; dup {
+			return MediaType{}, fmt.Errorf("%w: repeated parameter %s", ErrInvalidMediaType, name)
+		}
+		if m.Params == nil {
+			m.Params = make(map[string]string)
+		}
+		m.Params[name] = val
+	}
+	return m, nil
+}
+
+// SplitQuoted splits s at sep, except inside quoted strings. The parts are
+// not trimmed.
+func SplitQuoted(s string, sep byte) []string {
+	var parts []string
+	quoted, escaped, start := false, false, 0
+	for i := 0; i < len(s); i++ {
+		switch {
+		case escaped:
+			escaped = false
+		case quoted && s[i] == '\\':
+			escaped = true
+		case s[i] == '"':
+			quoted = !quoted
+		case !quoted && s[i] == sep:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// unquote returns the content of a quoted-string with its quoted-pairs
+// resolved. s has to be exactly one quoted-string.
+func unquote(s string) (string, bool) {
+	if len(s) < 2 || s[0] != '"' || s[len(s)-1] != '"' {
+		return "", false
+	}
+	s = s[1 : len(s)-1]
+	if !strings.ContainsAny(s, `\"`) {
+		return s, true
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '"':
+			return "", false
+		case '\\':
+			i++
+			if i == len(s) {
+				return "", false
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String(), true
+}
@@ -0,0 +1,102 @@
+package headers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetInt64(t *testing.T) {
+	h := NewHeaders()
+	h.Add("Content-Length", "1024")
+	h.Add("Max", "9223372036854775807")
+	h.Add("Overflow", "9223372036854775808")
+	h.Add("Negative", "-1")
+	h.Add("Spaced", "1 2")
+	h.Add("Repeated", "5")
+	h.Add("Repeated", "5")
+
+	n, err := h.GetInt64("content-length")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1024), n)
+	n, err = h.GetInt64("Max")
+	require.NoError(t, err)
+	assert.Equal(t, int64(9223372036854775807), n)
+
+	for _, name := range []string{"Overflow", "Negative", "Spaced", "Repeated"} {
+		_, err = h.GetInt64(name)
+		require.ErrorIs(t, err, ErrInvalidNumber, name)
+	}
+	_, err = h.GetInt64("Missing")
+	require.ErrorIs(t, err, ErrMissingField)
+}
+
+func TestGetSetTime(t *testing.T) {
+	want := time.Date(1994, time.November, 6, 8, 49, 37, 0, time.UTC)
+	h := NewHeaders()
+
+	// Test: All three HTTP-date formats
+	for _, value := range []string{
+		"Sun, 06 Nov 1994 08:49:37 GMT",
+		"Sunday, 06-Nov-94 08:49:37 GMT",
+		"Sun Nov  6 08:49:37 1994",
+	} {
+		h.Set("Date", value)
+		got, err := h.GetTime("Date")
+		require.NoError(t, err, value)
+		assert.True(t, want.Equal(got), value)
+	}
+
+	// Test: Invalid and missing dates
+	h.Set("Date", "yesterday")
+	_, err := h.GetTime("Date")
+	require.ErrorIs(t, err, ErrInvalidDate)
+	_, err = h.GetTime("Expires")
+	require.ErrorIs(t, err, ErrMissingField)
+
+	// Test: SetTime writes an IMF-fixdate in GMT
+	require.NoError(t, h.SetTime("Last-Modified", want.In(time.FixedZone("CET", 3600))))
+	value, _ := h.Get("Last-Modified")
+	assert.Equal(t, "Sun, 06 Nov 1994 08:49:37 GMT", value)
+}
+
+func TestGetList(t *testing.T) {
+	h := NewHeaders()
+	h.Add("Accept-Encoding", "gzip, , deflate ")
+	h.Add("accept-encoding", "br")
+	h.Add("If-Match", `"a,b", W/"c"`)
+	h.Add("X-Quoted", `"say \"hi, there\"", x`)
+
+	assert.Equal(t, []string{"gzip", "deflate", "br"}, h.GetList("Accept-Encoding"))
+	assert.Equal(t, []string{`"a,b"`, `W/"c"`}, h.GetList("If-Match"))
+	assert.Equal(t, []string{`"say \"hi, there\""`, "x"}, h.GetList("X-Quoted"))
+	assert.Nil(t, h.GetList("Missing"))
+}
+
+func TestGetMediaType(t *testing.T) {
+	tests := []struct {
+		value string
+		want  MediaType
+	}{
+		{"text/html", MediaType{Type: "text", Subtype: "html"}},
+		{"Text/HTML; Charset=utf-8", MediaType{Type: "text", Subtype: "html", Params: map[string]string{"charset": "utf-8"}}},
+		{`multipart/form-data; boundary="a;b\"c"`, MediaType{Type: "multipart", Subtype: "form-data", Params: map[string]string{"boundary": `a;b"c`}}},
+		{"application/json ; q=1 ;", MediaType{Type: "application", Subtype: "json", Params: map[string]string{"q": "1"}}},
+	}
+	for _, tc := range tests {
+		h := NewHeaders()
+		h.Set("Content-Type", tc.value)
+		got, err := h.GetMediaType("Content-Type")
+		require.NoError(t, err, tc.value)
+		assert.Equal(t, tc.want, got, tc.value)
+	}
+
+	for _, value := range []string{"", "text", "text/", "/html", "text/html; charset", "text/html; charset=a b", `text/html; charset="utf-8`, "text/html; a=1; A=2"} {
+		_, err := ParseMediaType(value)
+		require.ErrorIs(t, err, ErrInvalidMediaType, value)
+	}
+	_, err := NewHeaders().GetMediaType("Content-Type")
+	require.ErrorIs(t, err, ErrMissingField)
+}
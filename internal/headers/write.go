@@ -0,0 +1,103 @@
+package headers
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// WriteOptions controls how field lines are serialized.
+type WriteOptions struct {
+	// Canonical writes names in canonical form, "content-length" as
+	// "Content-Length". Otherwise names keep the casing they were given.
+	Canonical bool
+	// Exclude lists fields to leave out, matched case-insensitively.
+	Exclude []string
+}
+
+// Write writes h as a complete section: its field lines in order followed
+// by the empty line. Nothing is written if a field fails validation.
+func (h *Headers) Write(w io.Writer, opts WriteOptions) error {
+	buf, err := h.AppendFields(make([]byte, 0, 256), opts)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(buf, crlf...))
+	return err
+}
+
+// AppendFields appends the field lines of h to buf in order, without the
+// empty line that ends a section, so callers can add lines of their own.
+func (h *Headers) AppendFields(buf []byte, opts WriteOptions) ([]byte, error) {
+	var err error
+	for name, value := range h.All() {
+		if excluded(opts.Exclude, name) {
+			continue
+		}
+		if buf, err = AppendField(buf, name, value, opts.Canonical); err != nil {
+			return nil, err
+		}
+	}
+	return buf, nil
+}
+
+// AppendField appends one "name: value" line to buf. The name has to be a
+// token, and the value may not contain CR, LF or NUL, which would let it
+// add field lines of its own or split the message.
+func AppendField(buf []byte, name, value string, canonical bool) ([]byte, error) {
+	if !isValidKey(name) {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidHeaderName, name)
+	}
+	if strings.ContainsAny(value, "\r\n\x00") {
+		return nil, fmt.Errorf("%w: CR, LF or NUL in %s", ErrInvalidHeaderValue, name)
+	}
+	if canonical {
+		name = CanonicalName(name)
+	}
+	buf = append(buf, name...)
+	buf = append(buf, ": "...)
+	buf = append(buf, value...)
+	return append(buf, crlf...), nil
+}
+
+// CanonicalName returns name with the first letter and every letter after
+// a hyphen in upper case and the rest in lower case. Names that are not
+// tokens are returned unchanged.
+func CanonicalName(name string) string {
+	if !isValidKey(name) {
+		return name
+	}
+	upper := true
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		if upper && 'a' <= c && c <= 'z' || !upper && 'A' <= c && c <= 'Z' {
+			return canonicalize(name)
+		}
+		upper = c == '-'
+	}
+	return name
+}
+
+func canonicalize(name string) string {
+	b := []byte(name)
+	upper := true
+	for i, c := range b {
+		switch {
+		case upper && 'a' <= c && c <= 'z':
+			b[i] = c - ('a' - 'A')
+		case !upper && 'A' <= c && c <= 'Z':
+			b[i] = c + ('a' - 'A')
+		}
+		upper = c == '-'
+	}
+	return string(b)
+}
+
+func excluded(names []string, name string) bool {
+	for _, n := range names {
+		if strings.EqualFold(n, name) {
+			return true
+		}
+	}
+	return false
+}
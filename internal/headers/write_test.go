@@ -0,0 +1,69 @@
+package headers
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWrite(t *testing.T) {
+	h := NewHeaders()
+	h.Add("content-type", "text/plain")
+	h.Add("Set-Cookie", "a=1")
+	h.Add("X-REQUEST-ID", "42")
+	h.Add("set-cookie", "b=2")
+
+	// Test: Insertion order and casing are kept
+	var buf bytes.Buffer
+	require.NoError(t, h.Write(&buf, WriteOptions{}))
+	assert.Equal(t, "content-type: text/plain\r\nSet-Cookie: a=1\r\nX-REQUEST-ID: 42\r\nset-cookie: b=2\r\n\r\n", buf.String())
+
+	// Test: Canonical names
+	buf.Reset()
+	require.NoError(t, h.Write(&buf, WriteOptions{Canonical: true}))
+	assert.Equal(t, "Content-Type: text/plain\r\nSet-Cookie: a=1\r\nX-Request-Id: 42\r\nSet-Cookie: b=2\r\n\r\n", buf.String())
+
+	// Test: Excluded fields are left out
+	buf.Reset()
+	require.NoError(t, h.Write(&buf, WriteOptions{Exclude: []string{"SET-COOKIE"}}))
+	assert.Equal(t, "content-type: text/plain\r\nX-REQUEST-ID: 42\r\n\r\n", buf.String())
+
+	// Test: Empty section
+	buf.Reset()
+	require.NoError(t, NewHeaders().Write(&buf, WriteOptions{}))
+	assert.Equal(t, "\r\n", buf.String())
+
+	// Test: CR, LF and NUL in values are rejected and nothing is written
+	for _, value := range []string{"a\r\nX-Injected: b", "a\nb", "a\rb", "a\x00b"} {
+		bad := h.Clone()
+		bad.Add("X-Bad", value)
+		buf.Reset()
+		require.ErrorIs(t, bad.Write(&buf, WriteOptions{}), ErrInvalidHeaderValue, value)
+		assert.Empty(t, buf.String())
+	}
+
+	// Test: Invalid names are rejected
+	_, err := AppendField(nil, "Bad Name", "x", false)
+	require.ErrorIs(t, err, ErrInvalidHeaderName)
+}
+
+func TestCanonicalName(t *testing.T) {
+	tests := map[string]string{
+		"content-length":  "Content-Length",
+		"CONTENT-LENGTH":  "Content-Length",
+		"Content-Length":  "Content-Length",
+		"etag":            "Etag",
+		"x-forwarded-for": "X-Forwarded-For",
+		"sec-ch-ua":       "Sec-Ch-Ua",
+		"a--b":            "A--B",
+		"-x":              "-X",
+		"x_y":             "X_y",
+		"bad name":        "bad name",
+		"":                "",
+	}
+	for name, want := range tests {
+		assert.Equal(t, want, CanonicalName(name), name)
+	}
+}
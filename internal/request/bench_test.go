@@ -0,0 +1,98 @@
+package request
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+const benchGetRequest = "GET /api/v1/users?id=42&sort=asc HTTP/1.1\r\n" +
+	"Host: localhost:42069\r\n" +
+	"User-Agent: Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0 Safari/537.36\r\n" +
+	"Accept: text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8\r\n" +
+	"Accept-Language: en-US,en;q=0.5\r\n" +
+	"Accept-Encoding: gzip, deflate, br\r\n" +
+	"Connection: keep-alive\r\n" +
+	"Cookie: session=0123456789abcdef; theme=dark\r\n" +
+	"Cache-Control: max-age=0\r\n" +
+	"X-Request-Id: 7f3c2a9e-1b4d-4c8e-9f2a-6d5e4c3b2a19\r\n" +
+	"\r\n"
+
+var benchPostRequest = "POST /submit HTTP/1.1\r\n" +
+	"Host: localhost:42069\r\n" +
+	"Content-Type: application/json\r\n" +
+	"Content-Length: 1024\r\n" +
+	"\r\n" + strings.Repeat("x", 1024)
+
+var benchChunkedRequest = "POST /upload HTTP/1.1\r\n" +
+	"Host: localhost:42069\r\n" +
+	"Transfer-Encoding: chunked\r\n" +
+	"\r\n" + strings.Repeat("100\r\n"+strings.Repeat("y", 256)+"\r\n", 16) + "0\r\n\r\n"
+
+// repeatReader serves the same bytes forever so pipelined requests can be
+// read without allocating input.
+type repeatReader struct {
+	data []byte
+	pos  int
+}
+
+func (r *repeatReader) Read(p []byte) (int, error) {
+	n := copy(p, r.data[r.pos:])
+	r.pos = (r.pos + n) % len(r.data)
+	return n, nil
+}
+
+func benchmarkRequestFromReader(b *testing.B, data string) {
+	b.ReportAllocs()
+	b.SetBytes(int64(len(data)))
+	raw := []byte(data)
+	for i := 0; i < b.N; i++ {
+		r, err := RequestFromReader(bytes.NewReader(raw))
+		if err != nil {
+			b.Fatal(err)
+		}
+		if _, err := io.Copy(io.Discard, r.BodyReader); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func benchmarkPipelined(b *testing.B, data string) {
+	b.ReportAllocs()
+	b.SetBytes(int64(len(data)))
+	rr := NewReader(&repeatReader{data: []byte(data)}, DefaultParserConfig())
+	for i := 0; i < b.N; i++ {
+		r, err := rr.ReadRequest()
+		if err != nil {
+			b.Fatal(err)
+		}
+		if _, err := io.Copy(io.Discard, r.BodyReader); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkRequestFromReaderGet(b *testing.B) {
+	benchmarkRequestFromReader(b, benchGetRequest)
+}
+
+func BenchmarkRequestFromReaderPost(b *testing.B) {
+	benchmarkRequestFromReader(b, benchPostRequest)
+}
+
+func BenchmarkRequestFromReaderChunked(b *testing.B) {
+	benchmarkRequestFromReader(b, benchChunkedRequest)
+}
+
+func BenchmarkPipelinedGet(b *testing.B) {
+	benchmarkPipelined(b, benchGetRequest)
+}
+
+func BenchmarkPipelinedPost(b *testing.B) {
+	benchmarkPipelined(b, benchPostRequest)
+}
+
+func BenchmarkPipelinedChunked(b *testing.B) {
+	benchmarkPipelined(b, benchChunkedRequest)
+}
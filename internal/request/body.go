@@ -0,0 +1,114 @@
+package request
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+var errBodyClosed = errors.New("read on closed body")
+
+type bodyReader struct {
+	request *Request
+	reader  *Reader
+	pending []byte
+	closed  bool
+}
+
+func (b *bodyReader) Read(p []byte) (int, error) {
+	if b.closed {
+		return 0, errBodyClosed
+	}
+	return b.read(p)
+}
+
+func (b *bodyReader) read(p []byte) (int, error) {
+	rr := b.reader
+	parser := rr.parser
+	for len(b.pending) == 0 {
+		if parser.Request() != b.request {
+			return 0, io.EOF
+		}
+		b.pending = parser.TakeBody()
+		if len(b.pending) > 0 {
+			break
+		}
+		if parser.Done() {
+			return 0, io.EOF
+		}
+
+		nParsed, _, err := parser.Feed(rr.unparsed())
+		rr.consume(nParsed)
+		if err != nil {
+			return 0, err
+		}
+		if nParsed > 0 || parser.Done() {
+			continue
+		}
+
+		if err := rr.fill(); err != nil {
+			if errors.Is(err, io.EOF) {
+				return 0, parser.incompleteError()
+			}
+			return 0, fmt.Errorf("error reading body: %w", err)
+		}
+	}
+
+	n := copy(p, b.pending)
+	b.pending = b.pending[n:]
+	return n, nil
+}
+
+func (b *bodyReader) Close() error {
+	b.closed = true
+	return nil
+}
+
+// BodyDone reports whether all of the body has been read off the connection,
+// even if some of it has not been returned by BodyReader yet.
+func (r *Request) BodyDone() bool {
+	if r.body == nil {
+		return true
+	}
+	parser := r.body.reader.parser
+	return parser.Request() != r.body.request || parser.Done()
+}
+
+// ReadAll reads the rest of the body into Body. An empty body leaves Body nil.
+func (r *Request) ReadAll() ([]byte, error) {
+	if r.BodyReader == nil {
+		return r.Body, nil
+	}
+	body, err := io.ReadAll(r.BodyReader)
+	if err != nil {
+		return nil, err
+	}
+	if len(body) > 0 {
+		r.Body = append(r.Body, body...)
+	}
+	return r.Body, nil
+}
+
+// Discard drops whatever is left of the body, even if BodyReader was closed,
+// so the connection can carry another request. It fails with ErrBodyTooLong
+// if more than limit bytes remain; a negative limit means no limit.
+func (r *Request) Discard(limit int64) error {
+	if r.body == nil {
+		return nil
+	}
+	buf := make([]byte, 4096)
+	var discarded int64
+	for {
+		n, err := r.body.read(buf)
+		discarded += int64(n)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if limit >= 0 && discarded > limit {
+			return ErrBodyTooLong
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package request
+
+import (
+	"httpfromtcp/internal/headers"
+	"strings"
+)
+
+// ParserConfig caps how much of a request the parser will accept. A zero
+// value for any field disables that limit.
+type ParserConfig struct {
+	MaxRequestLineBytes int
+	MaxHeaderBytes      int
+	MaxHeaderCount      int
+	MaxBodyBytes        int64
+
+	// Strict rejects messages whose framing parsers are known to disagree
+	// on, following RFC 9112 section 6.3: Content-Length together with
+	// Transfer-Encoding, repeated Content-Length, Transfer-Encoding on
+	// HTTP/1.0, obs-fold, bare CR or LF, and repeated Host headers.
+	Strict bool
+
+	// Lenient accepts non-conforming syntax from legacy clients. It takes
+	// precedence over Strict for the syntax it covers; the leniencies a
+	// request needed are reported in Request.Leniencies.
+	Lenient Leniency
+
+	// DecodeContent makes BodyReader decompress gzip and deflate bodies.
+	// Content-Encoding and Content-Length are removed from the headers of
+	// a decoded request. Reads fail with ErrBodyTooLong past
+	// MaxDecodedBodyBytes and with ErrCompressionRatio once the body has
+	// grown more than MaxCompressionRatio times its compressed size.
+	// These errors surface while the handler reads the body, after the
+	// request has been handed over, so the handler has to answer them,
+	// normally with 413 Content Too Large.
+	DecodeContent       bool
+	MaxDecodedBodyBytes int64
+	MaxCompressionRatio int
+}
+
+// Leniency is a set of syntax deviations the parser can be told to accept.
+type Leniency uint8
+
+const (
+	// LenientBareLF ends lines at a bare LF as well as at CRLF.
+	LenientBareLF Leniency = 1 << iota
+	// LenientObsFold unfolds obs-fold continuation lines in headers and
+	// trailers.
+	LenientObsFold
+	// LenientWhitespace accepts runs of spaces and tabs between the parts
+	// of the request line.
+	LenientWhitespace
+	// LenientMethod accepts any token as a method, such as lowercase
+	// methods, instead of only uppercase letters.
+	LenientMethod
+
+	LenientAll = LenientBareLF | LenientObsFold | LenientWhitespace | LenientMethod
+)
+
+var leniencyNames = []struct {
+	flag Leniency
+	name string
+}{
+	{LenientBareLF, "bare-lf"},
+	{LenientObsFold, "obs-fold"},
+	{LenientWhitespace, "whitespace"},
+	{LenientMethod, "method"},
+}
+
+func (l Leniency) Has(flag Leniency) bool {
+	return l&flag == flag
+}
+
+func (l Leniency) String() string {
+	var names []string
+	for _, n := range leniencyNames {
+		if l&n.flag != 0 {
+			names = append(names, n.name)
+		}
+	}
+	return strings.Join(names, ",")
+}
+
+func (l Leniency) headers() headers.Leniency {
+	var h headers.Leniency
+	if l&LenientBareLF != 0 {
+		h |= headers.LenientBareLF
+	}
+	if l&LenientObsFold != 0 {
+		h |= headers.LenientObsFold
+	}
+	return h
+}
+
+func fromHeaders(h headers.Leniency) Leniency {
+	var l Leniency
+	if h&headers.LenientBareLF != 0 {
+		l |= LenientBareLF
+	}
+	if h&headers.LenientObsFold != 0 {
+		l |= LenientObsFold
+	}
+	return l
+}
+
+func DefaultParserConfig() ParserConfig {
+	return ParserConfig{
+		MaxRequestLineBytes: 8 << 10,
+		MaxHeaderBytes:      64 << 10,
+		MaxHeaderCount:      100,
+		Strict:              true,
+		MaxDecodedBodyBytes: 10 << 20,
+		MaxCompressionRatio: 100,
+	}
+}
+
+// LenientParserConfig is DefaultParserConfig with every leniency enabled.
+// Framing checks stay strict.
+func LenientParserConfig() ParserConfig {
+	config := DefaultParserConfig()
+	config.Lenient = LenientAll
+	return config
+}
@@ -0,0 +1,138 @@
+package request
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// minRatioCheckBytes is how much has to be decoded before the compression
+// ratio is enforced; tiny bodies of repeated bytes compress very well
+// without being a threat.
+const minRatioCheckBytes = 64 << 10
+
+// decodeContent replaces BodyReader with a decoder when the request has a
+// Content-Encoding made only of codings we know. Content-Encoding and
+// Content-Length then describe the compressed body, so both are removed.
+func (r *Request) decodeContent(config ParserConfig) {
+	if _, ok := r.Headers.Get("Content-Encoding"); !ok {
+		return
+	}
+	var codings []string
+	for _, coding := range r.Headers.GetList("Content-Encoding") {
+		coding = strings.ToLower(coding)
+		switch coding {
+		case "identity":
+		case "gzip", "x-gzip", "deflate":
+			codings = append(codings, coding)
+		default:
+			return
+		}
+	}
+	r.Headers.Del("Content-Encoding")
+	r.Headers.Del("Content-Length")
+	if len(codings) == 0 {
+		return
+	}
+
+	raw := &countingReader{reader: r.BodyReader}
+	var decoded io.Reader = raw
+	// Codings are listed in the order they were applied.
+	for i := len(codings) - 1; i >= 0; i-- {
+		decoded = &lazyDecoder{reader: decoded, coding: codings[i]}
+	}
+	r.BodyReader = &decodedBody{
+		raw:      raw,
+		decoded:  decoded,
+		closer:   r.BodyReader,
+		maxBytes: config.MaxDecodedBodyBytes,
+		maxRatio: int64(config.MaxCompressionRatio),
+	}
+}
+
+type countingReader struct {
+	reader io.Reader
+	n      int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.reader.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// lazyDecoder opens its decoder on the first Read, because gzip and zlib read
+// their header straight away and the body must not be touched before the
+// handler asks for it.
+type lazyDecoder struct {
+	reader  io.Reader
+	coding  string
+	decoder io.Reader
+}
+
+func (d *lazyDecoder) Read(p []byte) (int, error) {
+	if d.decoder == nil {
+		decoder, err := newDecoder(d.reader, d.coding)
+		if err != nil {
+			return 0, fmt.Errorf("%w: %w", ErrMalformedContent, err)
+		}
+		d.decoder = decoder
+	}
+	n, err := d.decoder.Read(p)
+	if err != nil && err != io.EOF {
+		err = fmt.Errorf("%w: %w", ErrMalformedContent, err)
+	}
+	return n, err
+}
+
+func newDecoder(r io.Reader, coding string) (io.Reader, error) {
+	if coding != "deflate" {
+		return gzip.NewReader(r)
+	}
+	// deflate is meant to be zlib-wrapped, but some clients send raw
+	// deflate data. A zlib header is a multiple of 31 with method 8.
+	br := bufio.NewReader(r)
+	header, err := br.Peek(2)
+	if err == nil && header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 {
+		return zlib.NewReader(br)
+	}
+	return flate.NewReader(br), nil
+}
+
+type decodedBody struct {
+	raw      *countingReader
+	decoded  io.Reader
+	closer   io.Closer
+	maxBytes int64
+	maxRatio int64
+	n        int64
+	err      error
+}
+
+func (b *decodedBody) Read(p []byte) (int, error) {
+	if b.err != nil {
+		return 0, b.err
+	}
+	if b.maxBytes > 0 && int64(len(p)) > b.maxBytes-b.n+1 {
+		p = p[:b.maxBytes-b.n+1]
+	}
+	n, err := b.decoded.Read(p)
+	b.n += int64(n)
+	if b.maxBytes > 0 && b.n > b.maxBytes {
+		b.err = fmt.Errorf("%w: decoded body exceeds %d bytes", ErrBodyTooLong, b.maxBytes)
+		return 0, b.err
+	}
+	if b.maxRatio > 0 && b.n > minRatioCheckBytes && b.n > b.raw.n*b.maxRatio {
+		b.err = fmt.Errorf("%w: %d bytes decoded from %d", ErrCompressionRatio, b.n, b.raw.n)
+		return 0, b.err
+	}
+	return n, err
+}
+
+func (b *decodedBody) Close() error {
+	return b.closer.Close()
+}
@@ -0,0 +1,145 @@
+package request
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func compress(t *testing.T, coding string, data []byte) []byte {
+	var buf bytes.Buffer
+	var w interface {
+		Write([]byte) (int, error)
+		Close() error
+	}
+	switch coding {
+	case "gzip":
+		w = gzip.NewWriter(&buf)
+	case "deflate":
+		w = zlib.NewWriter(&buf)
+	case "raw-deflate":
+		fw, err := flate.NewWriter(&buf, flate.DefaultCompression)
+		require.NoError(t, err)
+		w = fw
+	}
+	_, err := w.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	return buf.Bytes()
+}
+
+func encodedRequest(contentEncoding string, body []byte) string {
+	return fmt.Sprintf("POST /upload HTTP/1.1\r\nHost: localhost\r\nContent-Encoding: %s\r\nContent-Length: %d\r\n\r\n%s",
+		contentEncoding, len(body), body)
+}
+
+func decodeConfig() ParserConfig {
+	config := DefaultParserConfig()
+	config.DecodeContent = true
+	return config
+}
+
+func TestDecodeContent(t *testing.T) {
+	payload := []byte(strings.Repeat("hello, compressed world! ", 20))
+
+	tests := []struct {
+		name     string
+		encoding string
+		body     []byte
+	}{
+		{"gzip", "gzip", compress(t, "gzip", payload)},
+		{"x-gzip", "x-gzip", compress(t, "gzip", payload)},
+		{"zlib deflate", "deflate", compress(t, "deflate", payload)},
+		{"raw deflate", "deflate", compress(t, "raw-deflate", payload)},
+		{"stacked codings", "deflate, gzip", compress(t, "gzip", compress(t, "deflate", payload))},
+		{"identity", "identity", payload},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			reader := &chunkReader{data: encodedRequest(tc.encoding, tc.body), numBytesPerRead: 7}
+			r, err := RequestFromReaderWithConfig(reader, decodeConfig())
+			require.NoError(t, err)
+			body, err := r.ReadAll()
+			require.NoError(t, err)
+			assert.Equal(t, payload, body)
+			_, ok := r.Headers.Get("Content-Encoding")
+			assert.False(t, ok)
+			_, ok = r.Headers.Get("Content-Length")
+			assert.False(t, ok)
+		})
+	}
+
+	// Test: Decoding is opt-in
+	gz := compress(t, "gzip", payload)
+	r, err := readFullRequest(&chunkReader{data: encodedRequest("gzip", gz), numBytesPerRead: 7})
+	require.NoError(t, err)
+	assert.Equal(t, gz, r.Body)
+	assert.Equal(t, "gzip", header(r.Headers, "content-encoding"))
+
+	// Test: Unknown codings are left alone
+	reader := &chunkReader{data: encodedRequest("br", []byte("opaque")), numBytesPerRead: 7}
+	r, err = RequestFromReaderWithConfig(reader, decodeConfig())
+	require.NoError(t, err)
+	body, err := r.ReadAll()
+	require.NoError(t, err)
+	assert.Equal(t, "opaque", string(body))
+	assert.Equal(t, "br", header(r.Headers, "content-encoding"))
+
+	// Test: Corrupt data
+	reader = &chunkReader{data: encodedRequest("gzip", []byte("not gzip at all")), numBytesPerRead: 7}
+	r, err = RequestFromReaderWithConfig(reader, decodeConfig())
+	require.NoError(t, err)
+	_, err = r.ReadAll()
+	require.ErrorIs(t, err, ErrMalformedContent)
+}
+
+func TestDecodeContentLimits(t *testing.T) {
+	bomb := compress(t, "gzip", make([]byte, 1<<20))
+
+	// Test: Compression ratio
+	config := decodeConfig()
+	config.MaxDecodedBodyBytes = 0
+	reader := &chunkReader{data: encodedRequest("gzip", bomb), numBytesPerRead: 64}
+	r, err := RequestFromReaderWithConfig(reader, config)
+	require.NoError(t, err)
+	_, err = r.ReadAll()
+	require.ErrorIs(t, err, ErrCompressionRatio)
+
+	// Test: Decoded size
+	config = decodeConfig()
+	config.MaxCompressionRatio = 0
+	config.MaxDecodedBodyBytes = 1000
+	reader = &chunkReader{data: encodedRequest("gzip", bomb), numBytesPerRead: 64}
+	r, err = RequestFromReaderWithConfig(reader, config)
+	require.NoError(t, err)
+	_, err = r.ReadAll()
+	require.ErrorIs(t, err, ErrBodyTooLong)
+
+	// Test: Small, highly compressible bodies are fine
+	config = decodeConfig()
+	small := compress(t, "gzip", make([]byte, 32<<10))
+	reader = &chunkReader{data: encodedRequest("gzip", small), numBytesPerRead: 64}
+	r, err = RequestFromReaderWithConfig(reader, config)
+	require.NoError(t, err)
+	body, err := r.ReadAll()
+	require.NoError(t, err)
+	assert.Len(t, body, 32<<10)
+
+	// Test: The compressed body is still discarded for the next request
+	data := encodedRequest("gzip", bomb) + "GET /next HTTP/1.1\r\nHost: localhost\r\n\r\n"
+	rr := NewReader(&chunkReader{data: data, numBytesPerRead: 64}, decodeConfig())
+	r, err = rr.ReadRequest()
+	require.NoError(t, err)
+	_, err = r.ReadAll()
+	require.ErrorIs(t, err, ErrCompressionRatio)
+	next, err := rr.ReadRequest()
+	require.NoError(t, err)
+	assert.Equal(t, "/next", next.RequestLine.RequestTarget)
+}
@@ -0,0 +1,80 @@
+package request
+
+import (
+	"errors"
+	"fmt"
+	"httpfromtcp/internal/headers"
+	"io"
+)
+
+var (
+	ErrMalformedRequestLine        = errors.New("malformed request line")
+	ErrInvalidMethod               = errors.New("invalid method")
+	ErrInvalidTarget               = errors.New("invalid request target")
+	ErrUnsupportedVersion          = errors.New("unsupported HTTP version")
+	ErrMissingHost                 = errors.New("missing Host header")
+	ErrInvalidHost                 = errors.New("invalid Host header")
+	ErrInvalidHeaderName           = headers.ErrInvalidHeaderName
+	ErrMalformedHeader             = headers.ErrMalformedHeader
+	ErrInvalidHeaderValue          = headers.ErrInvalidHeaderValue
+	ErrObsFold                     = headers.ErrObsFold
+	ErrBareLineEnding              = headers.ErrBareLineEnding
+	ErrInvalidContentLength        = errors.New("invalid content length")
+	ErrUnsupportedTransferEncoding = errors.New("unsupported transfer encoding")
+	ErrConflictingFraming          = errors.New("conflicting message framing")
+	ErrMalformedChunk              = errors.New("malformed chunk")
+	ErrIncompleteRequest           = errors.New("incomplete request")
+
+	ErrRequestLineTooLong = errors.New("request line too long")
+	ErrHeadersTooLarge    = errors.New("request headers too large")
+	ErrTooManyHeaders     = errors.New("too many request headers")
+	ErrBodyTooLong        = errors.New("request body too long")
+	ErrCompressionRatio   = errors.New("request body compression ratio too high")
+	ErrMalformedContent   = errors.New("malformed content encoding")
+)
+
+// ParseError reports a request the client got wrong, as opposed to a failure
+// reading from the connection. Offset is the position in the message where
+// the offending element starts and State names the part being parsed.
+type ParseError struct {
+	Err    error
+	Offset int64
+	State  string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("%v (at byte %d, parsing %s)", e.Err, e.Offset, e.State)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+func (p *Parser) incompleteError() error {
+	return &ParseError{
+		Err:    fmt.Errorf("%w: %w", ErrIncompleteRequest, io.ErrUnexpectedEOF),
+		Offset: p.offset,
+		State:  p.status.String(),
+	}
+}
+
+func (s requestStatus) String() string {
+	switch s {
+	case requestStatusInitialized:
+		return "request line"
+	case RequestStatusParsingHeaders:
+		return "headers"
+	case requestStatusParsingBody:
+		return "body"
+	case requestStatusParsingChunkSize:
+		return "chunk size"
+	case requestStatusParsingChunkData, requestStatusParsingChunkDataEnd:
+		return "chunk data"
+	case requestStatusParsingTrailers:
+		return "trailers"
+	case requestStatusDone:
+		return "done"
+	default:
+		return "unknown"
+	}
+}
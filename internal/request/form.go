@@ -0,0 +1,166 @@
+package request
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"httpfromtcp/internal/headers"
+	"io"
+	"mime/multipart"
+	"net/url"
+)
+
+// maxFormBytes caps how much of an urlencoded body ParseForm reads into memory.
+const maxFormBytes = 10 << 20
+
+var (
+	ErrNotMultipart    = errors.New("request Content-Type isn't multipart/form-data")
+	ErrMissingBoundary = errors.New("no multipart boundary param in Content-Type")
+)
+
+type MultipartForm struct {
+	Value map[string][]string
+	File  map[string][]*FileHeader
+
+	form *multipart.Form
+}
+
+// RemoveAll deletes the temporary files holding file parts that did not fit
+// in memory.
+func (f *MultipartForm) RemoveAll() error {
+	return f.form.RemoveAll()
+}
+
+type FileHeader struct {
+	Filename string
+	Header   *headers.Headers
+	Size     int64
+
+	fileHeader *multipart.FileHeader
+}
+
+func (fh *FileHeader) Open() (multipart.File, error) {
+	return fh.fileHeader.Open()
+}
+
+// ParseForm fills Form with the query parameters and, for urlencoded POST,
+// PUT and PATCH bodies, fills PostForm with the body fields. Body values come
+// first in Form. Calling it again does nothing.
+func (r *Request) ParseForm() error {
+	if r.Form != nil {
+		return nil
+	}
+	r.PostForm = url.Values{}
+	if isBodyMethod(r.RequestLine.Method) {
+		mediaType, _ := r.contentType()
+		if mediaType.Type == "application" && mediaType.Subtype == "x-www-form-urlencoded" {
+			body, err := r.readFormBody()
+			if err != nil {
+				return err
+			}
+			r.PostForm, err = url.ParseQuery(string(body))
+			if err != nil {
+				return fmt.Errorf("error parsing form body: %w", err)
+			}
+		}
+	}
+
+	r.Form = url.Values{}
+	for name, values := range r.PostForm {
+		r.Form[name] = append(r.Form[name], values...)
+	}
+	for name, values := range r.Query() {
+		r.Form[name] = append(r.Form[name], values...)
+	}
+	return nil
+}
+
+// ParseMultipartForm parses a multipart/form-data body. Up to maxMemory bytes
+// of file parts are kept in memory; the rest are written to temporary files.
+// Plain fields are also added to Form and PostForm.
+func (r *Request) ParseMultipartForm(maxMemory int64) error {
+	if r.MultipartForm != nil {
+		return nil
+	}
+	mediaType, err := r.contentType()
+	if err != nil || mediaType.Type != "multipart" || mediaType.Subtype != "form-data" {
+		return ErrNotMultipart
+	}
+	boundary, ok := mediaType.Params["boundary"]
+	if !ok || boundary == "" {
+		return ErrMissingBoundary
+	}
+	if err := r.ParseForm(); err != nil {
+		return err
+	}
+
+	body := io.MultiReader(bytes.NewReader(r.Body), r.BodyReader)
+	form, err := multipart.NewReader(body, boundary).ReadForm(maxMemory)
+	if err != nil {
+		return fmt.Errorf("error parsing multipart form: %w", err)
+	}
+
+	r.MultipartForm = &MultipartForm{
+		Value: form.Value,
+		File:  map[string][]*FileHeader{},
+		form:  form,
+	}
+	for name, values := range form.Value {
+		r.Form[name] = append(r.Form[name], values...)
+		r.PostForm[name] = append(r.PostForm[name], values...)
+	}
+	for name, fileHeaders := range form.File {
+		for _, fh := range fileHeaders {
+			h := headers.NewHeaders()
+			for key, values := range fh.Header {
+				for _, value := range values {
+					h.Add(key, value)
+				}
+			}
+			r.MultipartForm.File[name] = append(r.MultipartForm.File[name], &FileHeader{
+				Filename:   fh.Filename,
+				Header:     h,
+				Size:       fh.Size,
+				fileHeader: fh,
+			})
+		}
+	}
+	return nil
+}
+
+// FormValue returns the first value for name from Form, parsing the form
+// first if needed.
+func (r *Request) FormValue(name string) string {
+	if r.Form == nil {
+		r.ParseForm()
+	}
+	return r.Form.Get(name)
+}
+
+func (r *Request) readFormBody() ([]byte, error) {
+	limit := int64(maxFormBytes)
+	if r.BodyReader == nil {
+		return r.Body, nil
+	}
+	body, err := io.ReadAll(io.LimitReader(r.BodyReader, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(body)) > limit {
+		return nil, ErrBodyTooLong
+	}
+	r.Body = append(r.Body, body...)
+	return r.Body, nil
+}
+
+func (r *Request) contentType() (headers.MediaType, error) {
+	mediaType, err := r.Headers.GetMediaType("Content-Type")
+	if errors.Is(err, headers.ErrMissingField) {
+		return headers.MediaType{}, nil
+	}
+	return mediaType, err
+}
+
+func isBodyMethod(method string) bool {
+	return method == "POST" || method == "PUT" || method == "PATCH"
+}
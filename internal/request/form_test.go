@@ -0,0 +1,108 @@
+package request
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func requestWithBody(t *testing.T, target, contentType, body string) *Request {
+	reader := &chunkReader{
+		data: "POST " + target + " HTTP/1.1\r\n" +
+			"Host: localhost:42069\r\n" +
+			"Content-Type: " + contentType + "\r\n" +
+			fmt.Sprintf("Content-Length: %d\r\n", len(body)) +
+			"\r\n" +
+			body,
+		numBytesPerRead: 7,
+	}
+	r, err := RequestFromReader(reader)
+	require.NoError(t, err)
+	return r
+}
+
+func TestParseForm(t *testing.T) {
+	// Test: Urlencoded body and query merged, body first
+	r := requestWithBody(t, "/submit?name=query&page=2", "application/x-www-form-urlencoded",
+		"name=Jane+Doe&lang=go&lang=zig&note=a%26b")
+	require.NoError(t, r.ParseForm())
+	assert.Equal(t, []string{"Jane Doe"}, r.PostForm["name"])
+	assert.Equal(t, []string{"Jane Doe", "query"}, r.Form["name"])
+	assert.Equal(t, []string{"go", "zig"}, r.Form["lang"])
+	assert.Equal(t, "a&b", r.FormValue("note"))
+	assert.Equal(t, "2", r.FormValue("page"))
+	assert.NotContains(t, r.PostForm, "page")
+	assert.Equal(t, "name=Jane+Doe&lang=go&lang=zig&note=a%26b", string(r.Body))
+
+	// Test: Other content types leave the body alone
+	r = requestWithBody(t, "/submit?x=1", "application/json", `{"x":2}`)
+	require.NoError(t, r.ParseForm())
+	assert.Empty(t, r.PostForm)
+	assert.Equal(t, "1", r.FormValue("x"))
+	body, err := r.ReadAll()
+	require.NoError(t, err)
+	assert.Equal(t, `{"x":2}`, string(body))
+
+	// Test: Malformed body
+	r = requestWithBody(t, "/submit", "application/x-www-form-urlencoded", "a=%zz")
+	require.Error(t, r.ParseForm())
+}
+
+func TestParseMultipartForm(t *testing.T) {
+	boundary := "XyZ123"
+	bigFile := strings.Repeat("0123456789", 100)
+	body := "--" + boundary + "\r\n" +
+		"Content-Disposition: form-data; name=\"title\"\r\n" +
+		"\r\n" +
+		"holiday\r\n" +
+		"--" + boundary + "\r\n" +
+		"Content-Disposition: form-data; name=\"photo\"; filename=\"small.txt\"\r\n" +
+		"Content-Type: text/plain\r\n" +
+		"\r\n" +
+		"tiny\r\n" +
+		"--" + boundary + "\r\n" +
+		"Content-Disposition: form-data; name=\"photo\"; filename=\"big.bin\"\r\n" +
+		"Content-Type: application/octet-stream\r\n" +
+		"\r\n" +
+		bigFile + "\r\n" +
+		"--" + boundary + "--\r\n"
+
+	r := requestWithBody(t, "/upload?album=2024", "multipart/form-data; boundary="+boundary, body)
+	require.NoError(t, r.ParseMultipartForm(100))
+	defer r.MultipartForm.RemoveAll()
+
+	assert.Equal(t, []string{"holiday"}, r.MultipartForm.Value["title"])
+	assert.Equal(t, "holiday", r.FormValue("title"))
+	assert.Equal(t, "2024", r.FormValue("album"))
+
+	files := r.MultipartForm.File["photo"]
+	require.Len(t, files, 2)
+	assert.Equal(t, "small.txt", files[0].Filename)
+	contentType, _ := files[0].Header.Get("Content-Type")
+	assert.Equal(t, "text/plain", contentType)
+	assert.Equal(t, int64(4), files[0].Size)
+
+	assert.Equal(t, "big.bin", files[1].Filename)
+	assert.Equal(t, int64(len(bigFile)), files[1].Size)
+	f, err := files[1].Open()
+	require.NoError(t, err)
+	_, spilled := f.(*os.File)
+	assert.True(t, spilled)
+	content, err := io.ReadAll(f)
+	require.NoError(t, err)
+	f.Close()
+	assert.Equal(t, bigFile, string(content))
+
+	// Test: Not multipart
+	r = requestWithBody(t, "/upload", "text/plain", "hello")
+	require.ErrorIs(t, r.ParseMultipartForm(100), ErrNotMultipart)
+
+	// Test: Missing boundary
+	r = requestWithBody(t, "/upload", "multipart/form-data", "hello")
+	require.ErrorIs(t, r.ParseMultipartForm(100), ErrMissingBoundary)
+}
@@ -0,0 +1,159 @@
+package request
+
+import (
+	"errors"
+	"httpfromtcp/internal/headers"
+	"strconv"
+	"strings"
+)
+
+var ErrNotAcceptable = errors.New("no acceptable representation")
+
+// acceptItem is one element of an Accept-* field: a value, its parameters
+// other than q, and its weight.
+type acceptItem struct {
+	value  string
+	params map[string]string
+	q      float64
+}
+
+// NegotiateContentType returns the media type from offers that the Accept
+// header rates highest, preferring earlier offers on ties. More specific
+// media ranges override less specific ones, so "text/*;q=0.5, text/html"
+// rates text/html at 1. Without an Accept header the first offer wins.
+func (r *Request) NegotiateContentType(offers ...string) (string, error) {
+	return r.negotiate("Accept", offers, matchMediaRange)
+}
+
+// NegotiateEncoding returns the content coding from offers that the
+// Accept-Encoding header rates highest. identity is acceptable unless it is
+// excluded explicitly or by "*;q=0".
+func (r *Request) NegotiateEncoding(offers ...string) (string, error) {
+	return r.negotiate("Accept-Encoding", offers, matchCoding)
+}
+
+// NegotiateLanguage returns the language tag from offers that the
+// Accept-Language header rates highest, using basic filtering: a range
+// matches a tag equal to it or starting with it followed by "-".
+func (r *Request) NegotiateLanguage(offers ...string) (string, error) {
+	return r.negotiate("Accept-Language", offers, matchLanguage)
+}
+
+// matchFunc reports how specifically item matches offer, or -1 if it does
+// not.
+type matchFunc func(item acceptItem, offer string) int
+
+func (r *Request) negotiate(field string, offers []string, match matchFunc) (string, error) {
+	if len(offers) == 0 {
+		return "", ErrNotAcceptable
+	}
+	value, ok := r.Headers.Get(field)
+	if !ok {
+		return offers[0], nil
+	}
+	items := parseAccept(value)
+
+	best, bestQ := "", 0.0
+	for _, offer := range offers {
+		q, specificity := defaultQ(field, offer), -1
+		for _, item := range items {
+			if s := match(item, offer); s > specificity {
+				q, specificity = item.q, s
+			}
+		}
+		if q > bestQ {
+			best, bestQ = offer, q
+		}
+	}
+	if bestQ == 0 {
+		return "", ErrNotAcceptable
+	}
+	return best, nil
+}
+
+// defaultQ is the weight of an offer no element of the field matches.
+func defaultQ(field, offer string) float64 {
+	if field == "Accept-Encoding" && strings.EqualFold(offer, "identity") {
+		return 1
+	}
+	return 0
+}
+
+func parseAccept(value string) []acceptItem {
+	var items []acceptItem
+	for _, element := range headers.SplitQuoted(value, ',') {
+		parts := headers.SplitQuoted(element, ';')
+		item := acceptItem{value: strings.ToLower(strings.TrimSpace(parts[0])), q: 1}
+		if item.value == "" {
+			continue
+		}
+		for _, param := range parts[1:] {
+			name, val, _ := strings.Cut(param, "=")
+			name = strings.ToLower(strings.TrimSpace(name))
+			val = strings.Trim(strings.TrimSpace(val), `"`)
+			if name == "q" {
+				q, err := strconv.ParseFloat(val, 64)
+				if err != nil || q < 0 || q > 1 {
+					q = 0
+				}
+				item.q = q
+				// Parameters after the weight are accept-ext, not part
+				// of the media range.
+				break
+			}
+			if item.params == nil {
+				item.params = make(map[string]string)
+			}
+			item.params[name] = strings.ToLower(val)
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+func matchMediaRange(item acceptItem, offer string) int {
+	offerItems := parseAccept(offer)
+	if len(offerItems) == 0 {
+		return -1
+	}
+	o := offerItems[0]
+	rangeType, rangeSub, _ := strings.Cut(item.value, "/")
+	offerType, offerSub, _ := strings.Cut(o.value, "/")
+	specificity := 0
+	switch {
+	case rangeType == "*" && rangeSub == "*":
+	case rangeType == offerType && rangeSub == "*":
+		specificity = 1
+	case rangeType == offerType && rangeSub == offerSub:
+		specificity = 2
+	default:
+		return -1
+	}
+	for name, value := range item.params {
+		if o.params[name] != value {
+			return -1
+		}
+	}
+	return specificity*100 + len(item.params)
+}
+
+func matchCoding(item acceptItem, offer string) int {
+	switch {
+	case strings.EqualFold(item.value, offer):
+		return 1
+	case item.value == "*":
+		return 0
+	}
+	return -1
+}
+
+func matchLanguage(item acceptItem, offer string) int {
+	offer = strings.ToLower(offer)
+	switch {
+	case item.value == "*":
+		return 0
+	case offer == item.value, strings.HasPrefix(offer, item.value+"-"):
+		return len(item.value)
+	}
+	return -1
+}
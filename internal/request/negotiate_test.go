@@ -0,0 +1,109 @@
+package request
+
+import (
+	"testing"
+
+	"httpfromtcp/internal/headers"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func requestWithHeader(name, value string) *Request {
+	r := &Request{Headers: headers.NewHeaders()}
+	if name != "" {
+		r.Headers.Set(name, value)
+	}
+	return r
+}
+
+func TestNegotiateContentType(t *testing.T) {
+	offers := []string{"text/html", "application/json"}
+	tests := []struct {
+		accept string
+		want   string
+	}{
+		{"application/json", "application/json"},
+		{"text/html;q=0.8, application/json", "application/json"},
+		{"*/*", "text/html"},
+		{"application/*", "application/json"},
+		{"text/*;q=0.5, */*;q=0.9", "application/json"},
+		{"text/*;q=0.5, text/html, */*;q=0.1", "text/html"},
+		{"*/*;q=0.8, text/html;q=0", "application/json"},
+		{"Application/JSON", "application/json"},
+		{`application/json;q=0.9;ext="a,b", text/html;level=1`, "application/json"},
+		{"text/html;q=bogus, application/json;q=0.1", "application/json"},
+	}
+	for _, tc := range tests {
+		got, err := requestWithHeader("Accept", tc.accept).NegotiateContentType(offers...)
+		require.NoError(t, err, tc.accept)
+		assert.Equal(t, tc.want, got, tc.accept)
+	}
+
+	// Test: Without Accept the first offer wins
+	got, err := requestWithHeader("", "").NegotiateContentType(offers...)
+	require.NoError(t, err)
+	assert.Equal(t, "text/html", got)
+
+	// Test: Parameters in the media range have to be offered
+	got, err = requestWithHeader("Accept", "text/html;level=1").NegotiateContentType("text/html", "text/html;level=1")
+	require.NoError(t, err)
+	assert.Equal(t, "text/html;level=1", got)
+
+	// Test: Nothing acceptable
+	for _, accept := range []string{"image/png", "text/html;q=0, application/json;q=0", "*/*;q=0"} {
+		_, err = requestWithHeader("Accept", accept).NegotiateContentType(offers...)
+		require.ErrorIs(t, err, ErrNotAcceptable, accept)
+	}
+	_, err = requestWithHeader("", "").NegotiateContentType()
+	require.ErrorIs(t, err, ErrNotAcceptable)
+}
+
+func TestNegotiateEncoding(t *testing.T) {
+	offers := []string{"gzip", "deflate", "identity"}
+	tests := []struct {
+		accept string
+		want   string
+	}{
+		{"gzip, deflate, br", "gzip"},
+		{"deflate, gzip;q=0.5", "deflate"},
+		{"br", "identity"},
+		{"", "identity"},
+		{"*", "gzip"},
+		{"gzip;q=0, *;q=0.3", "deflate"},
+		{"GZIP", "gzip"},
+	}
+	for _, tc := range tests {
+		got, err := requestWithHeader("Accept-Encoding", tc.accept).NegotiateEncoding(offers...)
+		require.NoError(t, err, tc.accept)
+		assert.Equal(t, tc.want, got, tc.accept)
+	}
+
+	for _, accept := range []string{"br, identity;q=0", "br, *;q=0"} {
+		_, err := requestWithHeader("Accept-Encoding", accept).NegotiateEncoding(offers...)
+		require.ErrorIs(t, err, ErrNotAcceptable, accept)
+	}
+}
+
+func TestNegotiateLanguage(t *testing.T) {
+	offers := []string{"en-US", "fr", "de-CH"}
+	tests := []struct {
+		accept string
+		want   string
+	}{
+		{"fr-CH, fr;q=0.9, en;q=0.8, de;q=0.7, *;q=0.5", "fr"},
+		{"de", "de-CH"},
+		{"en-us", "en-US"},
+		{"en;q=0.5, de-CH", "de-CH"},
+		{"es, *;q=0.1", "en-US"},
+		{"de-CH;q=0.2, de;q=0.9", "de-CH"},
+	}
+	for _, tc := range tests {
+		got, err := requestWithHeader("Accept-Language", tc.accept).NegotiateLanguage(offers...)
+		require.NoError(t, err, tc.accept)
+		assert.Equal(t, tc.want, got, tc.accept)
+	}
+
+	_, err := requestWithHeader("Accept-Language", "es, en-GB").NegotiateLanguage(offers...)
+	require.ErrorIs(t, err, ErrNotAcceptable)
+}
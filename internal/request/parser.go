@@ -0,0 +1,338 @@
+package request
+
+import (
+	"bytes"
+	"fmt"
+	"httpfromtcp/internal/headers"
+	"strconv"
+	"strings"
+)
+
+type requestStatus int
+
+const (
+	requestStatusInitialized requestStatus = iota
+	RequestStatusParsingHeaders
+	requestStatusParsingBody
+	requestStatusParsingChunkSize
+	requestStatusParsingChunkData
+	requestStatusParsingChunkDataEnd
+	requestStatusParsingTrailers
+	requestStatusDone
+)
+
+// headerMapHint sizes the header list for a typical browser request, so it
+// does not have to grow while parsing.
+const headerMapHint = 16
+
+// Parser is the incremental state machine behind RequestFromReader. Bytes are
+// pushed in with Feed, so it can be driven by any read loop.
+type Parser struct {
+	config  ParserConfig
+	request *Request
+	status  requestStatus
+
+	offset        int64
+	headerBytes   int
+	headerCount   int
+	bodyRead      int64
+	bodyRemaining uint64
+	body          []byte
+
+	// block is the rest of a header section that arrived in full, converted
+	// to a string once so every name and value can be sliced out of it.
+	block string
+}
+
+func NewParser(config ParserConfig) *Parser {
+	p := &Parser{config: config}
+	p.Reset()
+	return p
+}
+
+// Reset discards all state so the parser can start on the next message. The
+// Request returned before the reset is left untouched.
+func (p *Parser) Reset() {
+	*p = Parser{
+		config: p.config,
+		request: &Request{
+			Headers:  headers.NewHeadersSize(headerMapHint),
+			Trailers: headers.NewHeaders(),
+		},
+		status: requestStatusInitialized,
+	}
+}
+
+// Feed parses as much of data as it can and returns how many bytes it used.
+// Unused bytes have to be fed again together with whatever arrives next. Feed
+// stops early once it has decoded a piece of body, so the piece can be handed
+// out by TakeBody without copying; no more body is decoded until it has been
+// taken. Once done is true the message is complete and any unused bytes
+// belong to the next one.
+func (p *Parser) Feed(data []byte) (n int, done bool, err error) {
+	for p.status != requestStatusDone {
+		status := p.status
+		if len(p.body) > 0 && (status == requestStatusParsingBody || status == requestStatusParsingChunkData) {
+			break
+		}
+		nSingle, err := p.parseSingle(data[n:])
+		if err != nil {
+			return n, false, &ParseError{Err: err, Offset: p.offset, State: p.status.String()}
+		}
+		n += nSingle
+		p.offset += int64(nSingle)
+		if nSingle == 0 && p.status == status {
+			break
+		}
+	}
+	return n, p.status == requestStatusDone, nil
+}
+
+// Request returns the message being parsed. Its request line and headers are
+// only meaningful once HeadersDone reports true.
+func (p *Parser) Request() *Request {
+	return p.request
+}
+
+func (p *Parser) HeadersDone() bool {
+	return p.status != requestStatusInitialized && p.status != RequestStatusParsingHeaders
+}
+
+func (p *Parser) Done() bool {
+	return p.status == requestStatusDone
+}
+
+// TakeBody returns the body bytes decoded since the last call. Content-Length
+// and chunked framing have already been removed. The bytes are a slice of
+// what was passed to Feed and are only valid until that memory is reused.
+func (p *Parser) TakeBody() []byte {
+	body := p.body
+	p.body = nil
+	return body
+}
+
+func (p *Parser) parseSingle(data []byte) (int, error) {
+	switch p.status {
+	case requestStatusInitialized:
+		requestLine, target, bytesRead, used, err := parseRequestLine(data, p.config)
+		if err != nil {
+			return 0, err
+		}
+		if p.config.MaxRequestLineBytes > 0 {
+			lineLen := bytesRead - len(crlf)
+			if used.Has(LenientBareLF) {
+				lineLen++
+			}
+			if bytesRead == 0 {
+				lineLen = len(data)
+			}
+			if lineLen > p.config.MaxRequestLineBytes {
+				return 0, ErrRequestLineTooLong
+			}
+		}
+		if bytesRead == 0 {
+			return 0, nil
+		}
+		p.request.RequestLine = *requestLine
+		p.request.URL = target
+		p.request.Leniencies |= used
+		p.status = RequestStatusParsingHeaders
+		return bytesRead, nil
+	case RequestStatusParsingHeaders:
+		bytesRead, doneHeaders, used, err := p.parseHeaderLine(data)
+		if err != nil {
+			return 0, err
+		}
+		p.request.Leniencies |= fromHeaders(used)
+		if err := p.checkHeaderLimits(data, bytesRead, doneHeaders); err != nil {
+			return 0, err
+		}
+		if doneHeaders {
+			if err := p.startBody(); err != nil {
+				return 0, err
+			}
+		}
+		return bytesRead, nil
+	case requestStatusParsingBody:
+		n := p.consumeBody(data)
+		if p.bodyRemaining == 0 {
+			p.status = requestStatusDone
+		}
+		return n, nil
+	case requestStatusParsingChunkSize:
+		idx, n := lineEnd(data, p.config.Lenient.Has(LenientBareLF))
+		if idx == -1 {
+			if len(data) > maxChunkSizeLineBytes {
+				return 0, fmt.Errorf("%w: chunk size line too long", ErrMalformedChunk)
+			}
+			return 0, nil
+		}
+		if p.config.Strict && bytes.ContainsAny(data[:idx], "\r\n") {
+			return 0, fmt.Errorf("%w: %w", ErrMalformedChunk, ErrBareLineEnding)
+		}
+		chunkSize, err := parseChunkSize(data[:idx])
+		if err != nil {
+			return 0, err
+		}
+		if chunkSize == 0 {
+			p.status = requestStatusParsingTrailers
+		} else {
+			p.bodyRemaining = chunkSize
+			p.status = requestStatusParsingChunkData
+		}
+		if n-idx == 1 {
+			p.request.Leniencies |= LenientBareLF
+		}
+		return n, nil
+	case requestStatusParsingChunkData:
+		n := p.consumeBody(data)
+		if p.config.MaxBodyBytes > 0 && p.bodyRead > p.config.MaxBodyBytes {
+			return 0, ErrBodyTooLong
+		}
+		if p.bodyRemaining == 0 {
+			p.status = requestStatusParsingChunkDataEnd
+		}
+		return n, nil
+	case requestStatusParsingChunkDataEnd:
+		if len(data) > 0 && data[0] == '\n' && p.config.Lenient.Has(LenientBareLF) {
+			p.request.Leniencies |= LenientBareLF
+			p.status = requestStatusParsingChunkSize
+			return 1, nil
+		}
+		if len(data) < len(crlf) {
+			return 0, nil
+		}
+		if !bytes.HasPrefix(data, []byte(crlf)) {
+			return 0, fmt.Errorf("%w: chunk data not terminated by CRLF", ErrMalformedChunk)
+		}
+		p.status = requestStatusParsingChunkSize
+		return len([]byte(crlf)), nil
+	case requestStatusParsingTrailers:
+		bytesRead, doneTrailers, used, err := p.request.Trailers.ParseWithOptions(data, p.headerOptions())
+		if err != nil {
+			return 0, err
+		}
+		p.request.Leniencies |= fromHeaders(used)
+		if err := p.checkHeaderLimits(data, bytesRead, doneTrailers); err != nil {
+			return 0, err
+		}
+		if doneTrailers {
+			p.status = requestStatusDone
+		}
+		return bytesRead, nil
+	case requestStatusDone:
+		return 0, fmt.Errorf("request already parsed")
+	default:
+		return 0, fmt.Errorf("unrecognized status")
+	}
+}
+
+// parseHeaderLine parses the next field of the header section. Once the
+// whole section is in data it is parsed from a single string.
+func (p *Parser) parseHeaderLine(data []byte) (int, bool, headers.Leniency, error) {
+	if p.block == "" && !p.config.Lenient.Has(LenientBareLF) {
+		if idx := bytes.Index(data, []byte(crlf+crlf)); idx > 0 {
+			p.block = string(data[:idx+2*len(crlf)])
+		}
+	}
+	if p.block == "" {
+		return p.request.Headers.ParseWithOptions(data, p.headerOptions())
+	}
+	n, done, used, err := p.request.Headers.ParseString(p.block, p.headerOptions())
+	p.block = p.block[n:]
+	return n, done, used, err
+}
+
+func (p *Parser) headerOptions() headers.ParseOptions {
+	return headers.ParseOptions{Strict: p.config.Strict, Lenient: p.config.Lenient.headers()}
+}
+
+// startBody picks the body framing once the headers are complete, following
+// RFC 9112 section 6.3.
+func (p *Parser) startBody() error {
+	r := p.request
+	strict := p.config.Strict
+	host, ok := r.Headers.Get("Host")
+	if !ok && r.RequestLine.ProtoAtLeast(1, 1) {
+		return ErrMissingHost
+	}
+	if strict && strings.Contains(host, ",") {
+		return fmt.Errorf("%w: repeated Host %q", ErrInvalidHost, host)
+	}
+	if r.URL.Host == "" {
+		r.URL.Host = host
+	}
+
+	transferEncoding, hasTransferEncoding := r.Headers.Get("Transfer-Encoding")
+	bodyLengthStr, hasContentLength := r.Headers.Get("Content-Length")
+	if hasTransferEncoding {
+		if hasContentLength {
+			if strict {
+				return fmt.Errorf("%w: both Content-Length and Transfer-Encoding", ErrConflictingFraming)
+			}
+			r.Headers.Del("Content-Length")
+			r.closeAfter = true
+		}
+		if !r.RequestLine.ProtoAtLeast(1, 1) {
+			if strict {
+				return fmt.Errorf("%w: Transfer-Encoding in HTTP/1.0 request", ErrConflictingFraming)
+			}
+			r.closeAfter = true
+		}
+		if !isChunked(transferEncoding) || strict && !strings.EqualFold(strings.TrimSpace(transferEncoding), "chunked") {
+			return fmt.Errorf("%w: %s", ErrUnsupportedTransferEncoding, transferEncoding)
+		}
+		p.status = requestStatusParsingChunkSize
+		return nil
+	}
+	if !hasContentLength {
+		p.status = requestStatusDone
+		return nil
+	}
+	contentLength, err := parseContentLength(bodyLengthStr, strict)
+	if err != nil {
+		return err
+	}
+	r.Headers.Set("Content-Length", strconv.FormatInt(contentLength, 10))
+	if p.config.MaxBodyBytes > 0 && contentLength > p.config.MaxBodyBytes {
+		return ErrBodyTooLong
+	}
+	if contentLength == 0 {
+		p.status = requestStatusDone
+		return nil
+	}
+	p.bodyRemaining = uint64(contentLength)
+	p.status = requestStatusParsingBody
+	return nil
+}
+
+func (p *Parser) consumeBody(data []byte) int {
+	n := uint64(len(data))
+	if n > p.bodyRemaining {
+		n = p.bodyRemaining
+	}
+	p.body = data[:n:n]
+	p.bodyRemaining -= n
+	p.bodyRead += int64(n)
+	return int(n)
+}
+
+func (p *Parser) checkHeaderLimits(data []byte, bytesRead int, done bool) error {
+	if bytesRead == 0 {
+		if p.config.MaxHeaderBytes > 0 && p.headerBytes+len(data) > p.config.MaxHeaderBytes {
+			return ErrHeadersTooLarge
+		}
+		return nil
+	}
+	p.headerBytes += bytesRead
+	if p.config.MaxHeaderBytes > 0 && p.headerBytes > p.config.MaxHeaderBytes {
+		return ErrHeadersTooLarge
+	}
+	if !done {
+		p.headerCount++
+		if p.config.MaxHeaderCount > 0 && p.headerCount > p.config.MaxHeaderCount {
+			return ErrTooManyHeaders
+		}
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package request
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParserFeed(t *testing.T) {
+	// Test: Whole message in one call, with the next message behind it
+	data := []byte("POST /upload HTTP/1.1\r\n" +
+		"Host: localhost:42069\r\n" +
+		"Content-Length: 5\r\n" +
+		"\r\n" +
+		"hello" +
+		"GET /next HTTP/1.1\r\n")
+	p := NewParser(DefaultParserConfig())
+	n, done, err := p.Feed(data)
+	require.NoError(t, err)
+	assert.True(t, done)
+	assert.Equal(t, len(data)-len("GET /next HTTP/1.1\r\n"), n)
+	assert.Equal(t, "POST", p.Request().RequestLine.Method)
+	assert.Equal(t, "hello", string(p.TakeBody()))
+	assert.Nil(t, p.TakeBody())
+
+	// Test: Feeding after completion consumes nothing
+	n, done, err = p.Feed(data[n:])
+	require.NoError(t, err)
+	assert.True(t, done)
+	assert.Equal(t, 0, n)
+
+	// Test: Reset starts the next message and leaves the old request alone
+	first := p.Request()
+	p.Reset()
+	next := []byte("GET /next HTTP/1.1\r\nHost: localhost:42069\r\n\r\n")
+	n, done, err = p.Feed(next)
+	require.NoError(t, err)
+	assert.True(t, done)
+	assert.Equal(t, len(next), n)
+	assert.Equal(t, "/next", p.Request().RequestLine.RequestTarget)
+	assert.Equal(t, "/upload", first.RequestLine.RequestTarget)
+
+	// Test: One byte at a time, re-feeding the unconsumed remainder
+	data = []byte("POST /upload HTTP/1.1\r\n" +
+		"Host: localhost:42069\r\n" +
+		"Transfer-Encoding: chunked\r\n" +
+		"\r\n" +
+		"5\r\nhello\r\n" +
+		"6\r\n world\r\n" +
+		"0\r\n" +
+		"\r\n")
+	p = NewParser(DefaultParserConfig())
+	var pending, body []byte
+	for i, c := range data {
+		pending = append(pending, c)
+		n, done, err = p.Feed(pending)
+		require.NoError(t, err)
+		pending = pending[n:]
+		body = append(body, p.TakeBody()...)
+		if i < len(data)-1 {
+			assert.False(t, done)
+		}
+	}
+	assert.True(t, done)
+	assert.True(t, p.Done())
+	assert.Empty(t, pending)
+	assert.Equal(t, "hello world", string(body))
+
+	// Test: Errors report where parsing stopped
+	p = NewParser(DefaultParserConfig())
+	n, done, err = p.Feed([]byte("GET / HTTP/1.1\r\nBad Header\r\n\r\n"))
+	require.ErrorIs(t, err, ErrMalformedHeader)
+	assert.False(t, done)
+	assert.Equal(t, 16, n)
+
+	// Test: Body pieces are handed out one at a time without copying
+	data = []byte("POST /upload HTTP/1.1\r\n" +
+		"Host: localhost:42069\r\n" +
+		"Transfer-Encoding: chunked\r\n" +
+		"\r\n" +
+		"5\r\nhello\r\n" +
+		"6\r\n world\r\n" +
+		"0\r\n" +
+		"\r\n")
+	p = NewParser(DefaultParserConfig())
+	n, done, err = p.Feed(data)
+	require.NoError(t, err)
+	assert.False(t, done)
+	piece := p.TakeBody()
+	assert.Equal(t, "hello", string(piece))
+	assert.Equal(t, &data[bytes.Index(data, []byte("hello"))], &piece[0])
+	n, done, err = p.Feed(data[n:])
+	require.NoError(t, err)
+	assert.True(t, done)
+	assert.Equal(t, " world", string(p.TakeBody()))
+	assert.Nil(t, p.TakeBody())
+}
+
+func TestParseChunkSize(t *testing.T) {
+	valid := map[string]uint64{
+		"0":                0,
+		"1a":               26,
+		"FF":               255,
+		"10;ext=value":     16,
+		"10 \t":            16,
+		"7fffffffffffffff": 1<<63 - 1,
+	}
+	for line, want := range valid {
+		size, err := parseChunkSize([]byte(line))
+		require.NoError(t, err, line)
+		assert.Equal(t, want, size, line)
+	}
+	for _, line := range []string{"", ";ext", "0x10", "-1", "1_0", "g", "8000000000000000"} {
+		_, err := parseChunkSize([]byte(line))
+		require.ErrorIs(t, err, ErrMalformedChunk, line)
+	}
+}
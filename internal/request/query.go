@@ -0,0 +1,143 @@
+package request
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+var ErrMissingParam = errors.New("missing parameter")
+
+// Path returns the percent-decoded path of the request target.
+func (r *Request) Path() string {
+	if r.URL == nil {
+		return ""
+	}
+	return r.URL.Path
+}
+
+// Query returns the parsed query string. "+" decodes to a space as in
+// application/x-www-form-urlencoded; malformed pairs are dropped.
+func (r *Request) Query() url.Values {
+	if r.query == nil {
+		r.query = url.Values{}
+		if r.URL != nil {
+			r.query, _ = url.ParseQuery(r.URL.RawQuery)
+		}
+	}
+	return r.query
+}
+
+// QueryValue returns the first value for name, or "" if there is none.
+func (r *Request) QueryValue(name string) string {
+	return r.Query().Get(name)
+}
+
+func (r *Request) QueryInt(name string) (int, error) {
+	value, err := r.queryValue(name)
+	if err != nil {
+		return 0, err
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("query parameter %s: %w", name, err)
+	}
+	return n, nil
+}
+
+func (r *Request) QueryInt64(name string) (int64, error) {
+	value, err := r.queryValue(name)
+	if err != nil {
+		return 0, err
+	}
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("query parameter %s: %w", name, err)
+	}
+	return n, nil
+}
+
+func (r *Request) QueryBool(name string) (bool, error) {
+	value, err := r.queryValue(name)
+	if err != nil {
+		return false, err
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("query parameter %s: %w", name, err)
+	}
+	return b, nil
+}
+
+func (r *Request) queryValue(name string) (string, error) {
+	values, ok := r.Query()[name]
+	if !ok || len(values) == 0 {
+		return "", fmt.Errorf("%w: %s", ErrMissingParam, name)
+	}
+	return values[0], nil
+}
+
+// MatchPath matches the request path against pattern and, on success, makes
+// the wildcards available through PathValue. A segment written as {name}
+// matches exactly one path segment; a final {name...} matches the rest of
+// the path, including an empty rest. Segments are split on the escaped
+// path and decoded one by one, so an encoded slash such as "a%2Fb" stays
+// within its segment.
+func (r *Request) MatchPath(pattern string) bool {
+	escaped := ""
+	if r.URL != nil {
+		escaped = r.URL.EscapedPath()
+	}
+	pathSegments := strings.Split(escaped, "/")
+	for i, segment := range pathSegments {
+		decoded, err := url.PathUnescape(segment)
+		if err != nil {
+			return false
+		}
+		pathSegments[i] = decoded
+	}
+	patternSegments := strings.Split(pattern, "/")
+	params := map[string]string{}
+	for i, segment := range patternSegments {
+		name, isWildcard := wildcardName(segment)
+		if isWildcard && strings.HasSuffix(name, "...") {
+			if i != len(patternSegments)-1 || i > len(pathSegments) {
+				return false
+			}
+			params[strings.TrimSuffix(name, "...")] = strings.Join(pathSegments[i:], "/")
+			r.pathParams = params
+			return true
+		}
+		if i >= len(pathSegments) {
+			return false
+		}
+		if isWildcard {
+			if pathSegments[i] == "" {
+				return false
+			}
+			params[name] = pathSegments[i]
+		} else if segment != pathSegments[i] {
+			return false
+		}
+	}
+	if len(pathSegments) != len(patternSegments) {
+		return false
+	}
+	r.pathParams = params
+	return true
+}
+
+func wildcardName(segment string) (string, bool) {
+	if len(segment) < 2 || segment[0] != '{' || segment[len(segment)-1] != '}' {
+		return "", false
+	}
+	return segment[1 : len(segment)-1], true
+}
+
+// PathValue returns the value of a wildcard matched by the last successful
+// MatchPath, or "" if there is none.
+func (r *Request) PathValue(name string) string {
+	return r.pathParams[name]
+}
@@ -0,0 +1,83 @@
+package request
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func requestForTarget(t *testing.T, target string) *Request {
+	reader := &chunkReader{
+		data:            "GET " + target + " HTTP/1.1\r\nHost: localhost:42069\r\n\r\n",
+		numBytesPerRead: 8,
+	}
+	r, err := RequestFromReader(reader)
+	require.NoError(t, err)
+	return r
+}
+
+func TestQueryAccessors(t *testing.T) {
+	r := requestForTarget(t, "/search%20page?q=hello+world&tag=a&tag=b%26c&limit=10&debug=true&bad=x")
+	assert.Equal(t, "/search page", r.Path())
+	assert.Equal(t, "hello world", r.QueryValue("q"))
+	assert.Equal(t, []string{"a", "b&c"}, r.Query()["tag"])
+	assert.Equal(t, "", r.QueryValue("missing"))
+
+	limit, err := r.QueryInt("limit")
+	require.NoError(t, err)
+	assert.Equal(t, 10, limit)
+
+	limit64, err := r.QueryInt64("limit")
+	require.NoError(t, err)
+	assert.Equal(t, int64(10), limit64)
+
+	debug, err := r.QueryBool("debug")
+	require.NoError(t, err)
+	assert.True(t, debug)
+
+	_, err = r.QueryInt("missing")
+	require.ErrorIs(t, err, ErrMissingParam)
+
+	_, err = r.QueryInt("bad")
+	require.Error(t, err)
+
+	// Test: Malformed pairs are dropped, the rest survive
+	r = requestForTarget(t, "/?a=1&b=%zz&c=3")
+	assert.Equal(t, "1", r.QueryValue("a"))
+	assert.Equal(t, "3", r.QueryValue("c"))
+	assert.NotContains(t, r.Query(), "b")
+}
+
+func TestMatchPath(t *testing.T) {
+	tests := []struct {
+		path    string
+		pattern string
+		match   bool
+		params  map[string]string
+	}{
+		{"/", "/", true, map[string]string{}},
+		{"/video", "/video", true, map[string]string{}},
+		{"/video/", "/video", false, nil},
+		{"/users/42", "/users/{id}", true, map[string]string{"id": "42"}},
+		{"/users/42/posts/7", "/users/{id}/posts/{post}", true, map[string]string{"id": "42", "post": "7"}},
+		{"/users/", "/users/{id}", false, nil},
+		{"/users", "/users/{id}", false, nil},
+		{"/users/42/extra", "/users/{id}", false, nil},
+		{"/httpbin/stream/5", "/httpbin/{path...}", true, map[string]string{"path": "stream/5"}},
+		{"/httpbin", "/httpbin/{path...}", true, map[string]string{"path": ""}},
+		{"/httpbinx", "/httpbin/{path...}", false, nil},
+		{"/files/a%20b", "/files/{name}", true, map[string]string{"name": "a b"}},
+		{"/files/a%2Fb", "/files/{name}", true, map[string]string{"name": "a/b"}},
+		{"/files/a%2Fb", "/files/{dir}/{name}", false, nil},
+		{"/files/a/b", "/files/{dir}/{name}", true, map[string]string{"dir": "a", "name": "b"}},
+		{"/vid%65o", "/video", true, map[string]string{}},
+	}
+	for _, tc := range tests {
+		r := requestForTarget(t, tc.path)
+		assert.Equal(t, tc.match, r.MatchPath(tc.pattern), "%s against %s", tc.path, tc.pattern)
+		for name, value := range tc.params {
+			assert.Equal(t, value, r.PathValue(name))
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package request
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// bufferPool holds read buffers of bufferSize bytes. Buffers that had to
+// grow for an unusually large message are not returned to it.
+var bufferPool = sync.Pool{
+	New: func() any {
+		buf := make([]byte, bufferSize)
+		return &buf
+	},
+}
+
+// Reader reads successive requests from one connection. Bytes read past the
+// end of a request stay buffered for the next call to ReadRequest, so
+// pipelined requests are not lost.
+type Reader struct {
+	reader  io.Reader
+	parser  *Parser
+	buffer  []byte
+	start   int
+	end     int
+	current *Request
+}
+
+func NewReader(reader io.Reader, config ParserConfig) *Reader {
+	return &Reader{
+		reader: reader,
+		parser: NewParser(config),
+		buffer: *bufferPool.Get().(*[]byte),
+	}
+}
+
+// Release hands the read buffer back for reuse by other connections. Neither
+// the Reader nor the body of its last request may be used afterwards.
+func (rr *Reader) Release() {
+	if rr.buffer == nil {
+		return
+	}
+	if len(rr.buffer) == bufferSize {
+		buf := rr.buffer
+		bufferPool.Put(&buf)
+	}
+	rr.buffer = nil
+	rr.start, rr.end = 0, 0
+}
+
+// ReadRequest parses the next request line and headers. Whatever is left of
+// the previous request's body is discarded first. It returns io.EOF if the
+// connection is closed cleanly before a new request starts.
+func (rr *Reader) ReadRequest() (*Request, error) {
+	if rr.current != nil {
+		if err := rr.current.Discard(-1); err != nil {
+			return nil, err
+		}
+		rr.current = nil
+	}
+
+	p := rr.parser
+	p.Reset()
+	for {
+		nParsed, _, err := p.Feed(rr.unparsed())
+		rr.consume(nParsed)
+		if err != nil {
+			return nil, err
+		}
+		if p.HeadersDone() {
+			break
+		}
+
+		if err := rr.fill(); err != nil {
+			if errors.Is(err, io.EOF) {
+				if p.offset == 0 && rr.start == rr.end {
+					return nil, io.EOF
+				}
+				return nil, p.incompleteError()
+			}
+			return nil, fmt.Errorf("error reading request: %w", err)
+		}
+	}
+
+	request := p.Request()
+	request.body = &bodyReader{request: request, reader: rr}
+	request.BodyReader = request.body
+	if p.config.DecodeContent {
+		request.decodeContent(p.config)
+	}
+	rr.current = request
+	return request, nil
+}
+
+func (rr *Reader) unparsed() []byte {
+	return rr.buffer[rr.start:rr.end]
+}
+
+// fill reads more input behind the unparsed bytes. It moves them to the front
+// of the buffer first if that makes room, which is only safe while no body
+// bytes handed out by the parser are waiting to be copied.
+func (rr *Reader) fill() error {
+	if rr.start == rr.end {
+		rr.start, rr.end = 0, 0
+	}
+	if rr.end == len(rr.buffer) {
+		if rr.start > 0 {
+			rr.end = copy(rr.buffer, rr.unparsed())
+			rr.start = 0
+		} else {
+			tempBuff := make([]byte, len(rr.buffer)*2)
+			copy(tempBuff, rr.buffer)
+			rr.buffer = tempBuff
+		}
+	}
+	nRead, err := rr.reader.Read(rr.buffer[rr.end:])
+	rr.end += nRead
+	if nRead > 0 {
+		return nil
+	}
+	return err
+}
+
+func (rr *Reader) consume(n int) {
+	rr.start += n
+}
+
+// KeepAlive reports whether the client is willing to send another request on
+// the same connection. HTTP/1.1 connections persist unless the client asks
+// to close; HTTP/1.0 ones only when keep-alive was asked for.
+func (r *Request) KeepAlive() bool {
+	if r.closeAfter {
+		return false
+	}
+	connection, _ := r.Headers.Get("Connection")
+	if !r.RequestLine.ProtoAtLeast(1, 1) {
+		return hasToken(connection, "keep-alive")
+	}
+	return !hasToken(connection, "close")
+}
+
+func hasToken(value, token string) bool {
+	for _, part := range strings.Split(value, ",") {
+		if strings.EqualFold(strings.TrimSpace(part), token) {
+			return true
+		}
+	}
+	return false
+}
@@ -2,204 +2,274 @@ package request
 
 import (
 	"bytes"
-	"errors"
+	"context"
+	"crypto/tls"
 	"fmt"
 	"httpfromtcp/internal/headers"
 	"io"
 	"net/url"
-	"strconv"
 	"strings"
-)
-
-type requestStatus int
-
-const (
-	requestStatusInitialized requestStatus = iota
-	RequestStatusParsingHeaders
-	requestStatusParsingBody
-	requestStatusDone
+	"time"
 )
 
 type Request struct {
 	RequestLine RequestLine
-	Headers     headers.Headers
+	URL         *url.URL
+	Headers     *headers.Headers
 	Body        []byte
-	status      requestStatus
+	BodyReader  io.ReadCloser
+	Trailers    *headers.Headers
+
+	Form          url.Values
+	PostForm      url.Values
+	MultipartForm *MultipartForm
+
+	// Leniencies lists the non-conforming syntax the request relied on.
+	// It is always empty unless ParserConfig.Lenient allowed some.
+	Leniencies Leniency
+
+	// RemoteAddr and LocalAddr are the network addresses of the client and
+	// of the server end of the connection, as set by the server.
+	RemoteAddr string
+	LocalAddr  string
+	// AcceptedAt is when the connection the request arrived on was accepted.
+	AcceptedAt time.Time
+	// Sequence numbers the requests on one connection, starting at 1.
+	Sequence int
+	// TLS is the state of the TLS connection the request arrived on, or nil
+	// for plain connections.
+	TLS *tls.ConnectionState
+
+	ctx        context.Context
+	body       *bodyReader
+	closeAfter bool
+	query      url.Values
+	pathParams map[string]string
 }
 
 type RequestLine struct {
 	HttpVersion   string
 	RequestTarget string
 	Method        string
+	TargetForm    TargetForm
+	Major         int
+	Minor         int
 }
 
-const crlf = "\r\n"
-const buffersize int = 8
-
-func RequestFromReader(reader io.Reader) (*Request, error) {
-
-	request := &Request{
-		status:  requestStatusInitialized,
-		Headers: headers.NewHeaders(),
-		Body:    make([]byte, 0),
-	}
-	buffer := make([]byte, buffersize)
-	readToIndex := 0
-
-	for request.status != requestStatusDone {
-		if readToIndex >= len(buffer) {
-			tempBuff := make([]byte, len(buffer)*2)
-			copy(tempBuff, buffer)
-			buffer = tempBuff
-		}
-
-		nRead, err := reader.Read(buffer[readToIndex:])
-		if err != nil {
-			if errors.Is(err, io.EOF) {
-				if request.status != requestStatusDone {
-					return nil, fmt.Errorf("Incomplete Request")
-				}
-				break
-			}
-			return nil, fmt.Errorf("error reading request: %v", err)
-		}
-		readToIndex += nRead
+// ProtoAtLeast reports whether the request version is major.minor or newer.
+func (rl RequestLine) ProtoAtLeast(major, minor int) bool {
+	return rl.Major > major || rl.Major == major && rl.Minor >= minor
+}
 
-		nParsed, err := request.parse(buffer[:readToIndex])
-		if err != nil {
-			return nil, fmt.Errorf("error parsing buffer: %v", err)
-		}
-		copy(buffer, buffer[nParsed:])
-		readToIndex -= nParsed
+// Context returns the request's context. It is never nil; requests that
+// were not given one use context.Background.
+func (r *Request) Context() context.Context {
+	if r.ctx != nil {
+		return r.ctx
 	}
-	return request, nil
+	return context.Background()
 }
 
-func (r *Request) parse(data []byte) (int, error) {
-	totalBytesParsed := 0
-	for r.status != requestStatusDone {
-		n, err := r.parseSingle(data[totalBytesParsed:])
-		if err != nil {
-			return 0, err
-		}
-		if n == 0 {
-			break
-		}
-		totalBytesParsed += n
+// WithContext returns a shallow copy of r using ctx, which must not be nil.
+// This is how values are attached to a request as it is passed along.
+func (r *Request) WithContext(ctx context.Context) *Request {
+	if ctx == nil {
+		panic("request: nil context")
 	}
-	return totalBytesParsed, nil
+	r2 := new(Request)
+	*r2 = *r
+	r2.ctx = ctx
+	return r2
 }
 
-func (r *Request) parseSingle(data []byte) (int, error) {
-	switch r.status {
-	case requestStatusInitialized:
-		requestLine, bytesRead, err := parseRequestLine(data)
-		if err != nil {
-			return 0, fmt.Errorf("Encountered an error parsing request line:\n %s", err)
-		}
-		if bytesRead == 0 {
-			return 0, nil
-		}
-		r.RequestLine = *requestLine
-		r.status = RequestStatusParsingHeaders
-		return bytesRead, nil
-	case RequestStatusParsingHeaders:
-		bytesRead, doneHeaders, err := r.Headers.Parse(data)
-		if err != nil {
-			return 0, err
-		}
-		if doneHeaders {
-			r.status = requestStatusParsingBody
-		}
-		return bytesRead, nil
-	case requestStatusParsingBody:
-		bodyLengthStr, ok := r.Headers.Get("Content-Length")
-		if !ok {
-			r.status = requestStatusDone
-			return 0, nil
-		}
-		contentLength, err := strconv.Atoi(bodyLengthStr)
-		if err != nil {
-			return 0, fmt.Errorf("Encountered an error parsing content length:\n %s", err)
-		}
-		if contentLength < 0 {
-			return 0, fmt.Errorf("Invalid content length: %v", contentLength)
-		}
-
-		r.Body = append(r.Body, data...)
-
-		if len(r.Body) > contentLength {
-			r.status = requestStatusDone
-			return 0, fmt.Errorf("Body longer than Content-Length header")
-		}
+const crlf = "\r\n"
+const bufferSize = 4 << 10
+const maxChunkSizeLineBytes = 4096
 
-		if len(r.Body) == contentLength {
-			r.status = requestStatusDone
-		}
-		return len(data), nil
+func RequestFromReader(reader io.Reader) (*Request, error) {
+	return RequestFromReaderWithConfig(reader, DefaultParserConfig())
+}
 
-	case requestStatusDone:
-		return 0, fmt.Errorf("request already parsed")
-	default:
-		return 0, fmt.Errorf("unrecognized status")
-	}
+func RequestFromReaderWithConfig(reader io.Reader, config ParserConfig) (*Request, error) {
+	return NewReader(reader, config).ReadRequest()
 }
 
-func parseRequestLine(data []byte) (*RequestLine, int, error) {
-	idx := bytes.Index(data, []byte(crlf))
+func parseRequestLine(data []byte, config ParserConfig) (*RequestLine, *url.URL, int, Leniency, error) {
+	var used Leniency
+	idx, n := lineEnd(data, config.Lenient.Has(LenientBareLF))
 	if idx == -1 {
-		return nil, 0, nil
+		return nil, nil, 0, 0, nil
+	}
+	if n-idx == 1 {
+		used |= LenientBareLF
+	}
+	if config.Strict && bytes.ContainsAny(data[:idx], "\r\n") {
+		return nil, nil, 0, 0, fmt.Errorf("%w: %w", ErrMalformedRequestLine, ErrBareLineEnding)
 	}
 	requestLineText := string(data[:idx])
-	requestLine, err := requestLineFromString(requestLineText)
+	requestLine, target, lineUsed, err := requestLineFromString(requestLineText, config.Lenient)
 	if err != nil {
-		return nil, 0, fmt.Errorf("couldnt get requestline from string: %s", err)
+		return nil, nil, 0, 0, err
+	}
+	return requestLine, target, n, used | lineUsed, nil
+}
+
+// lineEnd returns the length of the first line in data and the offset just
+// past its terminator, or -1 if the line is not complete yet.
+func lineEnd(data []byte, allowBareLF bool) (int, int) {
+	if !allowBareLF {
+		idx := bytes.Index(data, []byte(crlf))
+		if idx == -1 {
+			return -1, 0
+		}
+		return idx, idx + len(crlf)
+	}
+	idx := bytes.IndexByte(data, '\n')
+	if idx == -1 {
+		return -1, 0
+	}
+	if idx > 0 && data[idx-1] == '\r' {
+		return idx - 1, idx + 1
 	}
-	return requestLine, idx + len([]byte(crlf)), nil
+	return idx, idx + 1
 }
 
-func requestLineFromString(str string) (*RequestLine, error) {
+func requestLineFromString(str string, lenient Leniency) (*RequestLine, *url.URL, Leniency, error) {
+	var used Leniency
 
-	parts := strings.Split(str, " ")
-	if len(parts) != 3 {
-		return nil, fmt.Errorf("malformed request line")
+	parts, ok := splitRequestLine(str)
+	if !ok && lenient.Has(LenientWhitespace) {
+		fields := strings.FieldsFunc(str, func(r rune) bool {
+			return r == ' ' || r == '\t'
+		})
+		if len(fields) == 3 {
+			parts, ok = [3]string(fields), true
+		}
+		used |= LenientWhitespace
+	}
+	if !ok {
+		return nil, nil, 0, fmt.Errorf("%w: %q", ErrMalformedRequestLine, str)
 	}
 
 	method := parts[0]
 	if len(method) == 0 {
-		return nil, fmt.Errorf("empty method: %s", method)
+		return nil, nil, 0, fmt.Errorf("%w: empty method", ErrInvalidMethod)
 	}
 	for _, c := range method {
-		if c < 'A' || c > 'Z' {
-			return nil, fmt.Errorf("invalid method: %s", method)
+		if c >= 'A' && c <= 'Z' {
+			continue
+		}
+		if !lenient.Has(LenientMethod) || !headers.IsToken(method) {
+			return nil, nil, 0, fmt.Errorf("%w: %s", ErrInvalidMethod, method)
 		}
+		used |= LenientMethod
+		break
 	}
 
 	requestTarget := parts[1]
-	if _, err := url.ParseRequestURI(requestTarget); err != nil {
-		return nil, err
+	target, form, err := parseRequestTarget(method, requestTarget)
+	if err != nil {
+		return nil, nil, 0, err
 	}
 
 	httpVersion := parts[2]
-	versionParts := strings.Split(httpVersion, "/")
-
-	if len(versionParts) != 2 {
-		return nil, fmt.Errorf("malformed start line: %s", httpVersion)
+	httpPart, version, found := strings.Cut(httpVersion, "/")
+	if !found || httpPart != "HTTP" || strings.Contains(version, "/") {
+		return nil, nil, 0, fmt.Errorf("%w: %s", ErrMalformedRequestLine, httpVersion)
 	}
-
-	httpPart := versionParts[0]
-
-	if httpPart != "HTTP" {
-		return nil, fmt.Errorf("unrecognized HTTP version: %s", httpPart)
+	major, minor, ok := parseVersionNumber(version)
+	if !ok {
+		return nil, nil, 0, fmt.Errorf("%w: %s", ErrMalformedRequestLine, httpVersion)
 	}
-	version := versionParts[1]
-	if version != "1.1" {
-		return nil, fmt.Errorf("unsupported HTTP verison: %s", version)
+	if major != 1 || minor > 1 {
+		return nil, nil, 0, fmt.Errorf("%w: %s", ErrUnsupportedVersion, httpVersion)
 	}
 
 	return &RequestLine{
 		Method:        method,
 		RequestTarget: requestTarget,
+		TargetForm:    form,
 		HttpVersion:   version,
-	}, nil
+		Major:         major,
+		Minor:         minor,
+	}, target, used, nil
+}
+
+// splitRequestLine splits str at single spaces into exactly three parts.
+func splitRequestLine(str string) ([3]string, bool) {
+	var parts [3]string
+	method, rest, ok1 := strings.Cut(str, " ")
+	target, version, ok2 := strings.Cut(rest, " ")
+	if !ok1 || !ok2 || strings.Contains(version, " ") {
+		return parts, false
+	}
+	parts[0], parts[1], parts[2] = method, target, version
+	return parts, true
+}
+
+func parseVersionNumber(version string) (int, int, bool) {
+	if len(version) != 3 || version[1] != '.' {
+		return 0, 0, false
+	}
+	major, minor := version[0], version[2]
+	if major < '0' || major > '9' || minor < '0' || minor > '9' {
+		return 0, 0, false
+	}
+	return int(major - '0'), int(minor - '0'), true
+}
+
+func isChunked(transferEncoding string) bool {
+	codings := strings.Split(transferEncoding, ",")
+	last := strings.TrimSpace(codings[len(codings)-1])
+	return strings.EqualFold(last, "chunked")
+}
+
+// parseContentLength accepts a list of identical lengths, which is what
+// repeated Content-Length fields turn into once merged, unless strict.
+func parseContentLength(value string, strict bool) (int64, error) {
+	values := strings.Split(value, ",")
+	if strict && len(values) > 1 {
+		return 0, fmt.Errorf("%w: repeated Content-Length %q", ErrInvalidContentLength, value)
+	}
+	var contentLength int64 = -1
+	for _, v := range values {
+		n, err := headers.ParseInt64(strings.TrimSpace(v))
+		if err != nil {
+			return 0, fmt.Errorf("%w: %q", ErrInvalidContentLength, value)
+		}
+		if contentLength != -1 && n != contentLength {
+			return 0, fmt.Errorf("%w: conflicting values %q", ErrInvalidContentLength, value)
+		}
+		contentLength = n
+	}
+	return contentLength, nil
+}
+
+func parseChunkSize(line []byte) (uint64, error) {
+	if idx := bytes.IndexByte(line, ';'); idx != -1 {
+		line = line[:idx]
+	}
+	line = bytes.TrimRight(line, " \t")
+	if len(line) == 0 {
+		return 0, fmt.Errorf("%w: empty chunk size", ErrMalformedChunk)
+	}
+	var size uint64
+	for _, c := range line {
+		var digit byte
+		switch {
+		case '0' <= c && c <= '9':
+			digit = c - '0'
+		case 'a' <= c && c <= 'f':
+			digit = c - 'a' + 10
+		case 'A' <= c && c <= 'F':
+			digit = c - 'A' + 10
+		default:
+			return 0, fmt.Errorf("%w: invalid chunk size %q", ErrMalformedChunk, line)
+		}
+		if size > (1<<63-1)>>4 {
+			return 0, fmt.Errorf("%w: invalid chunk size %q", ErrMalformedChunk, line)
+		}
+		size = size<<4 | uint64(digit)
+	}
+	return size, nil
 }
@@ -1,6 +1,9 @@
 package request
 
 import (
+	"context"
+	"errors"
+	"httpfromtcp/internal/headers"
 	"io"
 	"strings"
 	"testing"
@@ -9,6 +12,12 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
+// header returns the value of the named field, or "" if it is missing.
+func header(h *headers.Headers, name string) string {
+	value, _ := h.Get(name)
+	return value
+}
+
 type chunkReader struct {
 	data            string
 	numBytesPerRead int
@@ -167,15 +176,30 @@ func TestRequestLineParse(t *testing.T) {
 		require.Error(t, err)
 	}
 
-	// Test: Invalid HTTP version number
+	// Test: HTTP/1.0 request line
 	reader = &chunkReader{
 		data: "GET / HTTP/1.0\r\nHost: localhost:42069\r\n\r\n",
 	}
+	for i := 1; i <= len(reader.data); i++ {
+		reader.numBytesPerRead = i
+		reader.pos = 0
+		r, err := RequestFromReader(reader)
+		require.NoError(t, err)
+		require.NotNil(t, r)
+		assert.Equal(t, "1.0", r.RequestLine.HttpVersion)
+		assert.Equal(t, 1, r.RequestLine.Major)
+		assert.Equal(t, 0, r.RequestLine.Minor)
+	}
+
+	// Test: Invalid HTTP version number
+	reader = &chunkReader{
+		data: "GET / HTTP/1.2\r\nHost: localhost:42069\r\n\r\n",
+	}
 	for i := 1; i <= len(reader.data); i++ {
 		reader.numBytesPerRead = i
 		reader.pos = 0
 		_, err := RequestFromReader(reader)
-		require.Error(t, err)
+		require.ErrorIs(t, err, ErrUnsupportedVersion)
 	}
 
 	// Test: Missing request target
@@ -324,9 +348,9 @@ func TestHeadersParse(t *testing.T) {
 	r, err := RequestFromReader(reader)
 	require.NoError(t, err)
 	require.NotNil(t, r)
-	assert.Equal(t, "localhost:42069", r.Headers["host"])
-	assert.Equal(t, "curl/7.81.0", r.Headers["user-agent"])
-	assert.Equal(t, "*/*", r.Headers["accept"])
+	assert.Equal(t, "localhost:42069", header(r.Headers, "host"))
+	assert.Equal(t, "curl/7.81.0", header(r.Headers, "user-agent"))
+	assert.Equal(t, "*/*", header(r.Headers, "accept"))
 
 	// Test: Malformed Header
 	reader = &chunkReader{
@@ -347,7 +371,7 @@ func TestBodyParse(t *testing.T) {
 			"hello world!\n",
 		numBytesPerRead: 3,
 	}
-	r, err := RequestFromReader(reader)
+	r, err := readFullRequest(reader)
 	require.NoError(t, err)
 	require.NotNil(t, r)
 	assert.Equal(t, "hello world!\n", string(r.Body))
@@ -361,7 +385,7 @@ func TestBodyParse(t *testing.T) {
 			"partial content",
 		numBytesPerRead: 3,
 	}
-	r, err = RequestFromReader(reader)
+	r, err = readFullRequest(reader)
 	require.Error(t, err)
 
 	// Test: Empty Body, 0 reported content length
@@ -372,7 +396,7 @@ func TestBodyParse(t *testing.T) {
 			"\r\n",
 		numBytesPerRead: 3,
 	}
-	r, err = RequestFromReader(reader)
+	r, err = readFullRequest(reader)
 	require.NoError(t, err)
 	require.NotNil(t, r)
 	require.Nil(t, r.Body)
@@ -384,7 +408,7 @@ func TestBodyParse(t *testing.T) {
 			"\r\n",
 		numBytesPerRead: 3,
 	}
-	r, err = RequestFromReader(reader)
+	r, err = readFullRequest(reader)
 	require.NoError(t, err)
 	require.NotNil(t, r)
 	require.Nil(t, r.Body)
@@ -397,8 +421,666 @@ func TestBodyParse(t *testing.T) {
 			"Ignored content",
 		numBytesPerRead: 3,
 	}
-	r, err = RequestFromReader(reader)
+	r, err = readFullRequest(reader)
+	require.NoError(t, err)
+	require.NotNil(t, r)
+	require.Nil(t, r.Body)
+}
+
+func TestChunkedBodyParse(t *testing.T) {
+	// Test: Standard chunked body
+	reader := &chunkReader{
+		data: "POST /submit HTTP/1.1\r\n" +
+			"Host: localhost:42069\r\n" +
+			"Transfer-Encoding: chunked\r\n" +
+			"\r\n" +
+			"6\r\nhello \r\n" +
+			"7\r\nworld!\n\r\n" +
+			"0\r\n" +
+			"\r\n",
+	}
+	for i := 1; i <= len(reader.data); i++ {
+		reader.numBytesPerRead = i
+		reader.pos = 0
+		r, err := readFullRequest(reader)
+		require.NoError(t, err)
+		require.NotNil(t, r)
+		assert.Equal(t, "hello world!\n", string(r.Body))
+	}
+
+	// Test: Chunk extensions and uppercase hex sizes
+	reader = &chunkReader{
+		data: "POST /submit HTTP/1.1\r\n" +
+			"Host: localhost:42069\r\n" +
+			"Transfer-Encoding: chunked\r\n" +
+			"\r\n" +
+			"A;name=value\r\n0123456789\r\n" +
+			"0;last\r\n" +
+			"\r\n",
+		numBytesPerRead: 3,
+	}
+	r, err := readFullRequest(reader)
+	require.NoError(t, err)
+	require.NotNil(t, r)
+	assert.Equal(t, "0123456789", string(r.Body))
+
+	// Test: Trailers
+	reader = &chunkReader{
+		data: "POST /submit HTTP/1.1\r\n" +
+			"Host: localhost:42069\r\n" +
+			"Transfer-Encoding: chunked\r\n" +
+			"Trailer: X-Checksum\r\n" +
+			"\r\n" +
+			"5\r\nhello\r\n" +
+			"0\r\n" +
+			"X-Checksum: abc123\r\n" +
+			"\r\n",
+		numBytesPerRead: 3,
+	}
+	r, err = readFullRequest(reader)
+	require.NoError(t, err)
+	require.NotNil(t, r)
+	assert.Equal(t, "hello", string(r.Body))
+	trailer, _ := r.Trailers.Get("X-Checksum")
+	assert.Equal(t, "abc123", trailer)
+
+	// Test: Empty chunked body
+	reader = &chunkReader{
+		data: "POST /submit HTTP/1.1\r\n" +
+			"Host: localhost:42069\r\n" +
+			"Transfer-Encoding: chunked\r\n" +
+			"\r\n" +
+			"0\r\n" +
+			"\r\n",
+		numBytesPerRead: 3,
+	}
+	r, err = readFullRequest(reader)
 	require.NoError(t, err)
 	require.NotNil(t, r)
 	require.Nil(t, r.Body)
+
+	// Test: Transfer-Encoding takes precedence over Content-Length outside strict mode
+	reader = &chunkReader{
+		data: "POST /submit HTTP/1.1\r\n" +
+			"Host: localhost:42069\r\n" +
+			"Content-Length: 100\r\n" +
+			"Transfer-Encoding: chunked\r\n" +
+			"\r\n" +
+			"5\r\nhello\r\n" +
+			"0\r\n" +
+			"\r\n",
+		numBytesPerRead: 3,
+	}
+	config := DefaultParserConfig()
+	config.Strict = false
+	r, err = RequestFromReaderWithConfig(reader, config)
+	require.NoError(t, err)
+	require.NotNil(t, r)
+	_, err = r.ReadAll()
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(r.Body))
+	assert.False(t, r.KeepAlive())
+
+	// Test: Invalid chunk size
+	reader = &chunkReader{
+		data: "POST /submit HTTP/1.1\r\n" +
+			"Host: localhost:42069\r\n" +
+			"Transfer-Encoding: chunked\r\n" +
+			"\r\n" +
+			"zz\r\nhello\r\n" +
+			"0\r\n" +
+			"\r\n",
+		numBytesPerRead: 3,
+	}
+	_, err = readFullRequest(reader)
+	require.Error(t, err)
+
+	// Test: Chunk data longer than chunk size
+	reader = &chunkReader{
+		data: "POST /submit HTTP/1.1\r\n" +
+			"Host: localhost:42069\r\n" +
+			"Transfer-Encoding: chunked\r\n" +
+			"\r\n" +
+			"3\r\nhello\r\n" +
+			"0\r\n" +
+			"\r\n",
+		numBytesPerRead: 3,
+	}
+	_, err = readFullRequest(reader)
+	require.Error(t, err)
+
+	// Test: Missing terminating chunk
+	reader = &chunkReader{
+		data: "POST /submit HTTP/1.1\r\n" +
+			"Host: localhost:42069\r\n" +
+			"Transfer-Encoding: chunked\r\n" +
+			"\r\n" +
+			"5\r\nhello\r\n",
+		numBytesPerRead: 3,
+	}
+	_, err = readFullRequest(reader)
+	require.Error(t, err)
+
+	// Test: Unsupported transfer coding
+	reader = &chunkReader{
+		data: "POST /submit HTTP/1.1\r\n" +
+			"Host: localhost:42069\r\n" +
+			"Transfer-Encoding: gzip\r\n" +
+			"\r\n" +
+			"hello",
+		numBytesPerRead: 3,
+	}
+	_, err = readFullRequest(reader)
+	require.Error(t, err)
+}
+
+func TestBodyReader(t *testing.T) {
+	// Test: RequestFromReader returns before the body has been read
+	reader := io.MultiReader(
+		strings.NewReader("POST /upload HTTP/1.1\r\n"+
+			"Host: localhost:42069\r\n"+
+			"Content-Length: 10\r\n"+
+			"\r\n"+
+			"hello"),
+		&failingReader{},
+	)
+	r, err := RequestFromReader(reader)
+	require.NoError(t, err)
+	require.NotNil(t, r)
+	require.Nil(t, r.Body)
+	b := make([]byte, 3)
+	n, err := r.BodyReader.Read(b)
+	require.NoError(t, err)
+	assert.Equal(t, "hel", string(b[:n]))
+	n, err = r.BodyReader.Read(b)
+	require.NoError(t, err)
+	assert.Equal(t, "lo", string(b[:n]))
+	_, err = r.BodyReader.Read(b)
+	require.Error(t, err)
+
+	// Test: Body reader stops at Content-Length
+	reader = &chunkReader{
+		data: "POST /upload HTTP/1.1\r\n" +
+			"Host: localhost:42069\r\n" +
+			"Content-Length: 5\r\n" +
+			"\r\n" +
+			"helloGET / HTTP/1.1\r\n\r\n",
+		numBytesPerRead: 4,
+	}
+	r, err = RequestFromReader(reader)
+	require.NoError(t, err)
+	body, err := io.ReadAll(r.BodyReader)
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(body))
+
+	// Test: Streaming a chunked body
+	reader = &chunkReader{
+		data: "POST /upload HTTP/1.1\r\n" +
+			"Host: localhost:42069\r\n" +
+			"Transfer-Encoding: chunked\r\n" +
+			"\r\n" +
+			"5\r\nhello\r\n" +
+			"6\r\n world\r\n" +
+			"0\r\n" +
+			"\r\n",
+		numBytesPerRead: 2,
+	}
+	r, err = RequestFromReader(reader)
+	require.NoError(t, err)
+	body, err = io.ReadAll(r.BodyReader)
+	require.NoError(t, err)
+	assert.Equal(t, "hello world", string(body))
+
+	// Test: Reading a closed body
+	reader = &chunkReader{
+		data: "POST /upload HTTP/1.1\r\n" +
+			"Host: localhost:42069\r\n" +
+			"Content-Length: 5\r\n" +
+			"\r\n" +
+			"hello",
+		numBytesPerRead: 4,
+	}
+	r, err = RequestFromReader(reader)
+	require.NoError(t, err)
+	require.NoError(t, r.BodyReader.Close())
+	_, err = r.BodyReader.Read(b)
+	require.Error(t, err)
+}
+
+type failingReader struct{}
+
+func (failingReader) Read(p []byte) (int, error) {
+	return 0, errors.New("connection reset")
+}
+
+func readFullRequest(reader io.Reader) (*Request, error) {
+	r, err := RequestFromReader(reader)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := r.ReadAll(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func TestParserLimits(t *testing.T) {
+	config := ParserConfig{
+		MaxRequestLineBytes: 32,
+		MaxHeaderBytes:      64,
+		MaxHeaderCount:      3,
+		MaxBodyBytes:        8,
+	}
+
+	// Test: Request line within limit
+	reader := &chunkReader{
+		data:            "GET /short HTTP/1.1\r\nHost: localhost:42069\r\n\r\n",
+		numBytesPerRead: 3,
+	}
+	_, err := RequestFromReaderWithConfig(reader, config)
+	require.NoError(t, err)
+
+	// Test: Request line too long
+	reader = &chunkReader{
+		data:            "GET /" + strings.Repeat("a", 64) + " HTTP/1.1\r\nHost: localhost:42069\r\n\r\n",
+		numBytesPerRead: 3,
+	}
+	_, err = RequestFromReaderWithConfig(reader, config)
+	require.ErrorIs(t, err, ErrRequestLineTooLong)
+
+	// Test: Request line never terminated
+	reader = &chunkReader{
+		data:            "GET /" + strings.Repeat("a", 1024),
+		numBytesPerRead: 16,
+	}
+	_, err = RequestFromReaderWithConfig(reader, config)
+	require.ErrorIs(t, err, ErrRequestLineTooLong)
+
+	// Test: Header block too large
+	reader = &chunkReader{
+		data:            "GET / HTTP/1.1\r\nHost: localhost:42069\r\nX-Big: " + strings.Repeat("b", 64) + "\r\n\r\n",
+		numBytesPerRead: 3,
+	}
+	_, err = RequestFromReaderWithConfig(reader, config)
+	require.ErrorIs(t, err, ErrHeadersTooLarge)
+
+	// Test: Endless header line
+	reader = &chunkReader{
+		data:            "GET / HTTP/1.1\r\nX-Endless: " + strings.Repeat("c", 1024),
+		numBytesPerRead: 16,
+	}
+	_, err = RequestFromReaderWithConfig(reader, config)
+	require.ErrorIs(t, err, ErrHeadersTooLarge)
+
+	// Test: Too many headers
+	reader = &chunkReader{
+		data:            "GET / HTTP/1.1\r\nA: 1\r\nB: 2\r\nC: 3\r\nD: 4\r\n\r\n",
+		numBytesPerRead: 3,
+	}
+	_, err = RequestFromReaderWithConfig(reader, config)
+	require.ErrorIs(t, err, ErrTooManyHeaders)
+
+	// Test: Content-Length above body limit
+	reader = &chunkReader{
+		data:            "POST / HTTP/1.1\r\nHost: localhost:42069\r\nContent-Length: 9\r\n\r\n123456789",
+		numBytesPerRead: 3,
+	}
+	_, err = RequestFromReaderWithConfig(reader, config)
+	require.ErrorIs(t, err, ErrBodyTooLong)
+
+	// Test: Chunked body above body limit
+	reader = &chunkReader{
+		data:            "POST / HTTP/1.1\r\nHost: localhost:42069\r\nTransfer-Encoding: chunked\r\n\r\n5\r\n12345\r\n5\r\n67890\r\n0\r\n\r\n",
+		numBytesPerRead: 3,
+	}
+	r, err := RequestFromReaderWithConfig(reader, config)
+	require.NoError(t, err)
+	_, err = r.ReadAll()
+	require.ErrorIs(t, err, ErrBodyTooLong)
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   string
+		err    error
+		offset int64
+		state  string
+	}{
+		{
+			name:   "malformed request line",
+			data:   "/coffee HTTP/1.1\r\nHost: localhost:42069\r\n\r\n",
+			err:    ErrMalformedRequestLine,
+			offset: 0,
+			state:  "request line",
+		},
+		{
+			name:   "invalid method",
+			data:   "get / HTTP/1.1\r\nHost: localhost:42069\r\n\r\n",
+			err:    ErrInvalidMethod,
+			offset: 0,
+			state:  "request line",
+		},
+		{
+			name:   "invalid target",
+			data:   "GET invalid HTTP/1.1\r\nHost: localhost:42069\r\n\r\n",
+			err:    ErrInvalidTarget,
+			offset: 0,
+			state:  "request line",
+		},
+		{
+			name:   "unsupported version",
+			data:   "GET / HTTP/2.0\r\nHost: localhost:42069\r\n\r\n",
+			err:    ErrUnsupportedVersion,
+			offset: 0,
+			state:  "request line",
+		},
+		{
+			name:   "invalid header name",
+			data:   "GET / HTTP/1.1\r\nHost: localhost:42069\r\nH©st: localhost\r\n\r\n",
+			err:    ErrInvalidHeaderName,
+			offset: 39,
+			state:  "headers",
+		},
+		{
+			name:   "header without colon",
+			data:   "GET / HTTP/1.1\r\nInvalid\r\n\r\n",
+			err:    ErrMalformedHeader,
+			offset: 16,
+			state:  "headers",
+		},
+		{
+			name:   "invalid content length",
+			data:   "POST / HTTP/1.1\r\nHost: localhost:42069\r\nContent-Length: ten\r\n\r\n",
+			err:    ErrInvalidContentLength,
+			offset: 61,
+			state:  "headers",
+		},
+		{
+			name:   "missing host",
+			data:   "GET / HTTP/1.1\r\nAccept: */*\r\n\r\n",
+			err:    ErrMissingHost,
+			offset: 29,
+			state:  "headers",
+		},
+		{
+			name:   "incomplete request",
+			data:   "GET / HTTP/1.1\r\nHost: localhost",
+			err:    ErrIncompleteRequest,
+			offset: 16,
+			state:  "headers",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			reader := &chunkReader{data: tc.data, numBytesPerRead: 3}
+			_, err := RequestFromReader(reader)
+			require.ErrorIs(t, err, tc.err)
+			var parseErr *ParseError
+			require.ErrorAs(t, err, &parseErr)
+			assert.Equal(t, tc.offset, parseErr.Offset)
+			assert.Equal(t, tc.state, parseErr.State)
+		})
+	}
+
+	// Test: Malformed chunk reported while reading the body
+	reader := &chunkReader{
+		data: "POST / HTTP/1.1\r\n" +
+			"Host: localhost:42069\r\n" +
+			"Transfer-Encoding: chunked\r\n" +
+			"\r\n" +
+			"zz\r\nhello\r\n",
+		numBytesPerRead: 3,
+	}
+	r, err := RequestFromReader(reader)
+	require.NoError(t, err)
+	_, err = r.ReadAll()
+	require.ErrorIs(t, err, ErrMalformedChunk)
+	var parseErr *ParseError
+	require.ErrorAs(t, err, &parseErr)
+	assert.Equal(t, int64(70), parseErr.Offset)
+	assert.Equal(t, "chunk size", parseErr.State)
+
+	// Test: Connection errors are not parse errors
+	_, err = RequestFromReader(failingReader{})
+	require.Error(t, err)
+	require.False(t, errors.As(err, &parseErr))
+}
+
+func TestReaderPipelining(t *testing.T) {
+	reader := &chunkReader{
+		data: "POST /first HTTP/1.1\r\n" +
+			"Host: localhost:42069\r\n" +
+			"Content-Length: 5\r\n" +
+			"\r\n" +
+			"hello" +
+			"POST /second HTTP/1.1\r\n" +
+			"Host: localhost:42069\r\n" +
+			"Transfer-Encoding: chunked\r\n" +
+			"\r\n" +
+			"5\r\nworld\r\n0\r\n\r\n" +
+			"GET /third HTTP/1.1\r\n" +
+			"Host: localhost:42069\r\n" +
+			"\r\n",
+	}
+	for i := 1; i <= len(reader.data); i++ {
+		reader.numBytesPerRead = i
+		reader.pos = 0
+		rr := NewReader(reader, DefaultParserConfig())
+
+		r, err := rr.ReadRequest()
+		require.NoError(t, err)
+		assert.Equal(t, "/first", r.RequestLine.RequestTarget)
+		assert.True(t, r.KeepAlive())
+
+		// The second body is read, the first one is skipped
+		r, err = rr.ReadRequest()
+		require.NoError(t, err)
+		assert.Equal(t, "/second", r.RequestLine.RequestTarget)
+		body, err := r.ReadAll()
+		require.NoError(t, err)
+		assert.Equal(t, "world", string(body))
+
+		r, err = rr.ReadRequest()
+		require.NoError(t, err)
+		assert.Equal(t, "/third", r.RequestLine.RequestTarget)
+
+		_, err = rr.ReadRequest()
+		require.ErrorIs(t, err, io.EOF)
+	}
+
+	// Test: Connection: close
+	reader = &chunkReader{
+		data:            "GET / HTTP/1.1\r\nHost: localhost:42069\r\nConnection: Keep-Alive, Close\r\n\r\n",
+		numBytesPerRead: 3,
+	}
+	r, err := RequestFromReader(reader)
+	require.NoError(t, err)
+	assert.False(t, r.KeepAlive())
+}
+
+func TestHTTP10(t *testing.T) {
+	// Test: Host is optional and connections close by default
+	reader := &chunkReader{
+		data:            "GET / HTTP/1.0\r\nUser-Agent: probe\r\n\r\n",
+		numBytesPerRead: 3,
+	}
+	r, err := RequestFromReader(reader)
+	require.NoError(t, err)
+	assert.False(t, r.KeepAlive())
+	assert.True(t, r.RequestLine.ProtoAtLeast(1, 0))
+	assert.False(t, r.RequestLine.ProtoAtLeast(1, 1))
+
+	// Test: Keep-alive negotiated by the client
+	reader = &chunkReader{
+		data:            "GET / HTTP/1.0\r\nConnection: keep-alive\r\n\r\n",
+		numBytesPerRead: 3,
+	}
+	r, err = RequestFromReader(reader)
+	require.NoError(t, err)
+	assert.True(t, r.KeepAlive())
+}
+
+func TestRequestTargetForms(t *testing.T) {
+	tests := []struct {
+		name     string
+		line     string
+		form     TargetForm
+		path     string
+		rawQuery string
+		host     string
+	}{
+		{"origin", "GET /video?x=1 HTTP/1.1", TargetOrigin, "/video", "x=1", "localhost:42069"},
+		{"origin with escapes", "GET /a%20b/c%2Fd HTTP/1.1", TargetOrigin, "/a b/c/d", "", "localhost:42069"},
+		{"origin with empty segment", "GET //double HTTP/1.1", TargetOrigin, "//double", "", "localhost:42069"},
+		{"absolute", "GET http://example.com:8080/x?y=z HTTP/1.1", TargetAbsolute, "/x", "y=z", "example.com:8080"},
+		{"authority", "CONNECT example.com:443 HTTP/1.1", TargetAuthority, "", "", "example.com:443"},
+		{"asterisk", "OPTIONS * HTTP/1.1", TargetAsterisk, "*", "", "localhost:42069"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			reader := &chunkReader{
+				data:            tc.line + "\r\nHost: localhost:42069\r\n\r\n",
+				numBytesPerRead: 3,
+			}
+			r, err := RequestFromReader(reader)
+			require.NoError(t, err)
+			assert.Equal(t, tc.form, r.RequestLine.TargetForm)
+			require.NotNil(t, r.URL)
+			assert.Equal(t, tc.path, r.URL.Path)
+			assert.Equal(t, tc.rawQuery, r.URL.RawQuery)
+			assert.Equal(t, tc.host, r.URL.Host)
+		})
+	}
+
+	invalid := []string{
+		"GET * HTTP/1.1",
+		"CONNECT /path HTTP/1.1",
+		"CONNECT example.com HTTP/1.1",
+		"GET example.com:443 HTTP/1.1",
+		"GET /bad%zzescape HTTP/1.1",
+		"GET relative/path HTTP/1.1",
+	}
+	for _, line := range invalid {
+		reader := &chunkReader{
+			data:            line + "\r\nHost: localhost:42069\r\n\r\n",
+			numBytesPerRead: 3,
+		}
+		_, err := RequestFromReader(reader)
+		require.ErrorIs(t, err, ErrInvalidTarget, line)
+	}
+}
+
+func TestLenientParsing(t *testing.T) {
+	lenient := LenientParserConfig()
+
+	tests := []struct {
+		name       string
+		data       string
+		method     string
+		body       string
+		header     string
+		leniencies Leniency
+	}{
+		{
+			name:   "conforming request needs no leniency",
+			data:   "GET / HTTP/1.1\r\nHost: localhost\r\n\r\n",
+			method: "GET",
+			header: "localhost",
+		},
+		{
+			name:       "bare LF",
+			data:       "POST / HTTP/1.1\nHost: localhost\nContent-Length: 5\n\nhello",
+			method:     "POST",
+			header:     "localhost",
+			body:       "hello",
+			leniencies: LenientBareLF,
+		},
+		{
+			name:       "bare LF in chunked body",
+			data:       "POST / HTTP/1.1\r\nHost: localhost\r\nTransfer-Encoding: chunked\r\n\r\n5\nhello\n0\n\n",
+			method:     "POST",
+			header:     "localhost",
+			body:       "hello",
+			leniencies: LenientBareLF,
+		},
+		{
+			name:       "obs-fold",
+			data:       "GET / HTTP/1.1\r\nHost:\r\n localhost\r\n\r\n",
+			method:     "GET",
+			header:     "localhost",
+			leniencies: LenientObsFold,
+		},
+		{
+			name:       "multiple spaces",
+			data:       "GET  /\t HTTP/1.1\r\nHost: localhost\r\n\r\n",
+			method:     "GET",
+			header:     "localhost",
+			leniencies: LenientWhitespace,
+		},
+		{
+			name:       "lowercase method",
+			data:       "get / HTTP/1.1\r\nHost: localhost\r\n\r\n",
+			method:     "get",
+			header:     "localhost",
+			leniencies: LenientMethod,
+		},
+		{
+			name:       "everything at once",
+			data:       "m-search  /upnp HTTP/1.1\nHost: 239.255.255.250:1900\nST: ssdp:all\n  \n\n",
+			method:     "m-search",
+			header:     "239.255.255.250:1900",
+			leniencies: LenientAll,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			reader := &chunkReader{data: tc.data, numBytesPerRead: 3}
+			r, err := RequestFromReaderWithConfig(reader, lenient)
+			require.NoError(t, err)
+			_, err = r.ReadAll()
+			require.NoError(t, err)
+			assert.Equal(t, tc.method, r.RequestLine.Method)
+			assert.Equal(t, tc.header, header(r.Headers, "host"))
+			assert.Equal(t, tc.body, string(r.Body))
+			assert.Equal(t, tc.leniencies, r.Leniencies)
+		})
+	}
+
+	// Test: The default config rejects all of them
+	for _, tc := range tests[1:] {
+		reader := &chunkReader{data: tc.data, numBytesPerRead: 3}
+		_, err := readFullRequest(reader)
+		require.Error(t, err, tc.name)
+	}
+
+	// Test: Leniencies are enabled one at a time
+	config := DefaultParserConfig()
+	config.Lenient = LenientMethod
+	reader := &chunkReader{data: "get  / HTTP/1.1\r\nHost: localhost\r\n\r\n", numBytesPerRead: 3}
+	_, err := RequestFromReaderWithConfig(reader, config)
+	require.ErrorIs(t, err, ErrMalformedRequestLine)
+	reader = &chunkReader{data: "ge(t / HTTP/1.1\r\nHost: localhost\r\n\r\n", numBytesPerRead: 3}
+	_, err = RequestFromReaderWithConfig(reader, config)
+	require.ErrorIs(t, err, ErrInvalidMethod)
+
+	assert.Equal(t, "bare-lf,obs-fold,whitespace,method", LenientAll.String())
+	assert.Equal(t, "", Leniency(0).String())
+}
+
+func TestRequestContext(t *testing.T) {
+	r, err := RequestFromReader(strings.NewReader("GET / HTTP/1.1\r\nHost: localhost\r\n\r\n"))
+	require.NoError(t, err)
+	assert.Equal(t, context.Background(), r.Context())
+
+	type key struct{}
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), key{}, "value"))
+	r2 := r.WithContext(ctx)
+	assert.Equal(t, "value", r2.Context().Value(key{}))
+	assert.Equal(t, context.Background(), r.Context())
+	assert.Equal(t, r.RequestLine, r2.RequestLine)
+
+	cancel()
+	assert.ErrorIs(t, r2.Context().Err(), context.Canceled)
+	assert.Panics(t, func() { r.WithContext(nil) })
 }
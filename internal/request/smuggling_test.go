@@ -0,0 +1,225 @@
+package request
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Each payload is parsed in strict mode and with Strict turned off. A nil
+// error means the request must be accepted with the given body.
+func TestSmugglingPayloads(t *testing.T) {
+	tests := []struct {
+		name      string
+		headers   string
+		body      string
+		strictErr error
+		laxErr    error
+		laxBody   string
+	}{
+		{
+			name:      "CL.TE",
+			headers:   "POST / HTTP/1.1\r\nHost: a\r\nContent-Length: 6\r\nTransfer-Encoding: chunked\r\n",
+			body:      "0\r\n\r\nG",
+			strictErr: ErrConflictingFraming,
+		},
+		{
+			name:      "TE.CL",
+			headers:   "POST / HTTP/1.1\r\nHost: a\r\nTransfer-Encoding: chunked\r\nContent-Length: 3\r\n",
+			body:      "5\r\nhello\r\n0\r\n\r\n",
+			strictErr: ErrConflictingFraming,
+			laxBody:   "hello",
+		},
+		{
+			name:      "differing Content-Length",
+			headers:   "POST / HTTP/1.1\r\nHost: a\r\nContent-Length: 5\r\nContent-Length: 6\r\n",
+			body:      "hello!",
+			strictErr: ErrInvalidContentLength,
+			laxErr:    ErrInvalidContentLength,
+		},
+		{
+			name:      "repeated identical Content-Length",
+			headers:   "POST / HTTP/1.1\r\nHost: a\r\nContent-Length: 5\r\nContent-Length: 5\r\n",
+			body:      "hello",
+			strictErr: ErrInvalidContentLength,
+			laxBody:   "hello",
+		},
+		{
+			name:      "Content-Length list",
+			headers:   "POST / HTTP/1.1\r\nHost: a\r\nContent-Length: 5, 5\r\n",
+			body:      "hello",
+			strictErr: ErrInvalidContentLength,
+			laxBody:   "hello",
+		},
+		{
+			name:      "signed Content-Length",
+			headers:   "POST / HTTP/1.1\r\nHost: a\r\nContent-Length: +5\r\n",
+			body:      "hello",
+			strictErr: ErrInvalidContentLength,
+			laxErr:    ErrInvalidContentLength,
+		},
+		{
+			name:      "hex Content-Length",
+			headers:   "POST / HTTP/1.1\r\nHost: a\r\nContent-Length: 0x5\r\n",
+			body:      "hello",
+			strictErr: ErrInvalidContentLength,
+			laxErr:    ErrInvalidContentLength,
+		},
+		{
+			name:      "negative Content-Length",
+			headers:   "POST / HTTP/1.1\r\nHost: a\r\nContent-Length: -1\r\n",
+			strictErr: ErrInvalidContentLength,
+			laxErr:    ErrInvalidContentLength,
+		},
+		{
+			name:      "overflowing Content-Length",
+			headers:   "POST / HTTP/1.1\r\nHost: a\r\nContent-Length: 99999999999999999999\r\n",
+			strictErr: ErrInvalidContentLength,
+			laxErr:    ErrInvalidContentLength,
+		},
+		{
+			name:      "obfuscated Transfer-Encoding value",
+			headers:   "POST / HTTP/1.1\r\nHost: a\r\nTransfer-Encoding: xchunked\r\n",
+			body:      "0\r\n\r\n",
+			strictErr: ErrUnsupportedTransferEncoding,
+			laxErr:    ErrUnsupportedTransferEncoding,
+		},
+		{
+			name:      "chunked not last",
+			headers:   "POST / HTTP/1.1\r\nHost: a\r\nTransfer-Encoding: chunked, identity\r\n",
+			body:      "0\r\n\r\n",
+			strictErr: ErrUnsupportedTransferEncoding,
+			laxErr:    ErrUnsupportedTransferEncoding,
+		},
+		{
+			name:      "stacked transfer codings",
+			headers:   "POST / HTTP/1.1\r\nHost: a\r\nTransfer-Encoding: gzip, chunked\r\n",
+			body:      "5\r\nhello\r\n0\r\n\r\n",
+			strictErr: ErrUnsupportedTransferEncoding,
+			laxBody:   "hello",
+		},
+		{
+			name:      "space before colon",
+			headers:   "POST / HTTP/1.1\r\nHost: a\r\nTransfer-Encoding : chunked\r\n",
+			body:      "0\r\n\r\n",
+			strictErr: ErrInvalidHeaderName,
+			laxErr:    ErrInvalidHeaderName,
+		},
+		{
+			name:      "space inside name",
+			headers:   "POST / HTTP/1.1\r\nHost: a\r\nContent Length: 5\r\n",
+			body:      "hello",
+			strictErr: ErrInvalidHeaderName,
+			laxErr:    ErrInvalidHeaderName,
+		},
+		{
+			name:      "indented Transfer-Encoding",
+			headers:   "POST / HTTP/1.1\r\nHost: a\r\n Transfer-Encoding: chunked\r\n",
+			body:      "5\r\nhello\r\n0\r\n\r\n",
+			strictErr: ErrObsFold,
+			laxBody:   "hello",
+		},
+		{
+			name:      "obs-fold continuation",
+			headers:   "POST / HTTP/1.1\r\nHost: a\r\nX-Folded: one\r\n two\r\n",
+			strictErr: ErrObsFold,
+			laxErr:    ErrMalformedHeader,
+		},
+		{
+			name:      "bare LF between headers",
+			headers:   "POST / HTTP/1.1\r\nHost: a\nTransfer-Encoding: chunked\r\n",
+			body:      "0\r\n\r\n",
+			strictErr: ErrBareLineEnding,
+		},
+		{
+			name:      "bare CR in value",
+			headers:   "POST / HTTP/1.1\r\nHost: a\r\nX-Value: one\rtwo\r\n",
+			strictErr: ErrBareLineEnding,
+		},
+		{
+			name:      "NUL in value",
+			headers:   "POST / HTTP/1.1\r\nHost: a\r\nX-Value: one\x00two\r\n",
+			strictErr: ErrInvalidHeaderValue,
+		},
+		{
+			name:      "bare LF in request line",
+			headers:   "POST / HTTP/1.1\nHost: a\r\n",
+			strictErr: ErrMalformedRequestLine,
+			laxErr:    ErrMalformedRequestLine,
+		},
+		{
+			name:      "colon only on the next line",
+			headers:   "POST / HTTP/1.1\r\nHost: a\r\nX-No-Colon\r\nContent-Length: 5\r\n",
+			body:      "hello",
+			strictErr: ErrMalformedHeader,
+			laxErr:    ErrMalformedHeader,
+		},
+		{
+			name:      "Transfer-Encoding in HTTP/1.0",
+			headers:   "POST / HTTP/1.0\r\nTransfer-Encoding: chunked\r\n",
+			body:      "5\r\nhello\r\n0\r\n\r\n",
+			strictErr: ErrConflictingFraming,
+			laxBody:   "hello",
+		},
+		{
+			name:      "repeated Host",
+			headers:   "GET / HTTP/1.1\r\nHost: a\r\nHost: b\r\n",
+			strictErr: ErrInvalidHost,
+		},
+		{
+			name:      "bare LF in chunk size",
+			headers:   "POST / HTTP/1.1\r\nHost: a\r\nTransfer-Encoding: chunked\r\n",
+			body:      "5\nhello\r\n0\r\n\r\n",
+			strictErr: ErrMalformedChunk,
+			laxErr:    ErrMalformedChunk,
+		},
+		{
+			name:      "overflowing chunk size",
+			headers:   "POST / HTTP/1.1\r\nHost: a\r\nTransfer-Encoding: chunked\r\n",
+			body:      "ffffffffffffffffff\r\nhello\r\n0\r\n\r\n",
+			strictErr: ErrMalformedChunk,
+			laxErr:    ErrMalformedChunk,
+		},
+	}
+
+	strict := DefaultParserConfig()
+	lax := DefaultParserConfig()
+	lax.Strict = false
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			data := tc.headers + "\r\n" + tc.body
+
+			r, err := parseSmuggled(data, strict)
+			if tc.strictErr != nil {
+				require.ErrorIs(t, err, tc.strictErr, "strict")
+			} else {
+				require.NoError(t, err, "strict")
+			}
+
+			r, err = parseSmuggled(data, lax)
+			if tc.laxErr != nil {
+				require.ErrorIs(t, err, tc.laxErr, "lax")
+				return
+			}
+			require.NoError(t, err, "lax")
+			assert.Equal(t, tc.laxBody, string(r.Body))
+			if strings.Contains(tc.headers, "Transfer-Encoding") && strings.Contains(tc.headers, "Content-Length") {
+				assert.False(t, r.KeepAlive(), "connection must close after ambiguous framing")
+			}
+		})
+	}
+}
+
+func parseSmuggled(data string, config ParserConfig) (*Request, error) {
+	reader := &chunkReader{data: data, numBytesPerRead: 5}
+	r, err := RequestFromReaderWithConfig(reader, config)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := r.ReadAll(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
@@ -0,0 +1,85 @@
+package request
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+)
+
+// TargetForm is the shape of a request-target, see RFC 9112 section 3.2.
+type TargetForm int
+
+const (
+	// TargetOrigin is an absolute path with an optional query: /where?q=now
+	TargetOrigin TargetForm = iota
+	// TargetAbsolute is a full URI, as sent to proxies: http://example.com/
+	TargetAbsolute
+	// TargetAuthority is host and port, only used by CONNECT: example.com:443
+	TargetAuthority
+	// TargetAsterisk is a lone "*", only used by OPTIONS
+	TargetAsterisk
+)
+
+func (f TargetForm) String() string {
+	switch f {
+	case TargetOrigin:
+		return "origin-form"
+	case TargetAbsolute:
+		return "absolute-form"
+	case TargetAuthority:
+		return "authority-form"
+	case TargetAsterisk:
+		return "asterisk-form"
+	default:
+		return "unknown-form"
+	}
+}
+
+func parseRequestTarget(method, target string) (*url.URL, TargetForm, error) {
+	switch {
+	case method == "CONNECT":
+		host, port, err := net.SplitHostPort(target)
+		if err != nil || host == "" || port == "" || strings.ContainsAny(target, "/?#@") {
+			return nil, 0, fmt.Errorf("%w: CONNECT needs host:port, got %s", ErrInvalidTarget, target)
+		}
+		return &url.URL{Host: target}, TargetAuthority, nil
+	case target == "*":
+		if method != "OPTIONS" {
+			return nil, 0, fmt.Errorf("%w: * is only allowed for OPTIONS", ErrInvalidTarget)
+		}
+		return &url.URL{Path: "*"}, TargetAsterisk, nil
+	case strings.HasPrefix(target, "/"):
+		u, err := parseOriginForm(target)
+		if err != nil {
+			return nil, 0, fmt.Errorf("%w: %s", ErrInvalidTarget, target)
+		}
+		return u, TargetOrigin, nil
+	default:
+		u, err := url.ParseRequestURI(target)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return nil, 0, fmt.Errorf("%w: %s", ErrInvalidTarget, target)
+		}
+		return u, TargetAbsolute, nil
+	}
+}
+
+// parseOriginForm splits path and query itself because url.ParseRequestURI
+// reads a leading "//" as an authority, which origin-form never has.
+func parseOriginForm(target string) (*url.URL, error) {
+	for i := 0; i < len(target); i++ {
+		if target[i] < ' ' || target[i] == 0x7f {
+			return nil, fmt.Errorf("control character in target")
+		}
+	}
+	rawPath, rawQuery, _ := strings.Cut(target, "?")
+	path, err := url.PathUnescape(rawPath)
+	if err != nil {
+		return nil, err
+	}
+	u := &url.URL{Path: path, RawQuery: rawQuery}
+	if u.EscapedPath() != rawPath {
+		u.RawPath = rawPath
+	}
+	return u, nil
+}
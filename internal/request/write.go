@@ -0,0 +1,177 @@
+package request
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"httpfromtcp/internal/headers"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const writeChunkSize = 32 << 10
+
+// NewRequest builds an HTTP/1.1 request ready for Write. The body is sent
+// with Content-Length when its length can be read off the reader, which is
+// the case for *bytes.Buffer, *bytes.Reader and *strings.Reader, and chunked
+// otherwise. Framing headers already present in h are kept.
+func NewRequest(method, target string, h *headers.Headers, body io.Reader) (*Request, error) {
+	if !headers.IsToken(method) {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidMethod, method)
+	}
+	u, form, err := parseRequestTarget(method, target)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Request{
+		RequestLine: RequestLine{
+			Method:        method,
+			RequestTarget: target,
+			TargetForm:    form,
+			HttpVersion:   "1.1",
+			Major:         1,
+			Minor:         1,
+		},
+		URL:      u,
+		Headers:  h.Clone(),
+		Trailers: headers.NewHeaders(),
+	}
+
+	host, ok := r.Headers.Get("Host")
+	if !ok {
+		if u.Host == "" {
+			return nil, ErrMissingHost
+		}
+		host = u.Host
+		r.Headers.Set("Host", host)
+	}
+	if u.Host == "" {
+		u.Host = host
+	}
+
+	_, hasTransferEncoding := r.Headers.Get("Transfer-Encoding")
+	_, hasContentLength := r.Headers.Get("Content-Length")
+	if body != nil {
+		r.BodyReader = io.NopCloser(body)
+	}
+	if hasTransferEncoding || hasContentLength {
+		return r, nil
+	}
+	switch {
+	case body == nil:
+		if isBodyMethod(method) {
+			r.Headers.Set("Content-Length", "0")
+		}
+	case bodyLen(body) >= 0:
+		r.Headers.Set("Content-Length", strconv.Itoa(bodyLen(body)))
+	default:
+		r.Headers.Set("Transfer-Encoding", "chunked")
+	}
+	return r, nil
+}
+
+func bodyLen(body io.Reader) int {
+	switch b := body.(type) {
+	case *bytes.Buffer:
+		return b.Len()
+	case *bytes.Reader:
+		return b.Len()
+	case *strings.Reader:
+		return b.Len()
+	}
+	return -1
+}
+
+// Write sends the request in wire format, framed by its Transfer-Encoding or
+// Content-Length header. The body is whatever is in Body followed by what is
+// left of BodyReader, which Write consumes. Trailers are sent after a chunked
+// body.
+func (r *Request) Write(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	rl := r.RequestLine
+	version := rl.HttpVersion
+	if version == "" {
+		version = "1.1"
+	}
+	if _, err := fmt.Fprintf(bw, "%s %s HTTP/%s\r\n", rl.Method, rl.RequestTarget, version); err != nil {
+		return err
+	}
+	if err := writeFields(bw, r.Headers); err != nil {
+		return err
+	}
+
+	body := io.MultiReader(bytes.NewReader(r.Body), r.bodySource())
+	transferEncoding, hasTransferEncoding := r.Headers.Get("Transfer-Encoding")
+	contentLength, hasContentLength := r.Headers.Get("Content-Length")
+	switch {
+	case hasTransferEncoding:
+		if !isChunked(transferEncoding) {
+			return fmt.Errorf("%w: %s", ErrUnsupportedTransferEncoding, transferEncoding)
+		}
+		if err := writeChunked(bw, body); err != nil {
+			return err
+		}
+		if err := writeFields(bw, r.Trailers); err != nil {
+			return err
+		}
+	case hasContentLength:
+		n, err := parseContentLength(contentLength, true)
+		if err != nil {
+			return err
+		}
+		written, err := io.CopyN(bw, body, n)
+		if err != nil {
+			return fmt.Errorf("%w: body has %d of %d bytes", ErrInvalidContentLength, written, n)
+		}
+	}
+	return bw.Flush()
+}
+
+func (r *Request) bodySource() io.Reader {
+	if r.BodyReader == nil {
+		return bytes.NewReader(nil)
+	}
+	return r.BodyReader
+}
+
+func writeChunked(w io.Writer, body io.Reader) error {
+	buf := make([]byte, writeChunkSize)
+	for {
+		n, err := body.Read(buf)
+		if n > 0 {
+			if _, werr := fmt.Fprintf(w, "%x\r\n%s\r\n", n, buf[:n]); werr != nil {
+				return werr
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	_, err := io.WriteString(w, "0\r\n")
+	return err
+}
+
+// writeFields writes a header or trailer section, Host first and the rest
+// in order, followed by the empty line.
+func writeFields(w io.Writer, fields *headers.Headers) error {
+	var buf []byte
+	var err error
+	for name, value := range fields.All() {
+		if !strings.EqualFold(name, "Host") {
+			continue
+		}
+		if buf, err = headers.AppendField(buf, name, value, false); err != nil {
+			return err
+		}
+	}
+	if buf, err = fields.AppendFields(buf, headers.WriteOptions{Exclude: []string{"Host"}}); err != nil {
+		return err
+	}
+	_, err = w.Write(append(buf, crlf...))
+	return err
+}
@@ -0,0 +1,101 @@
+package request
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"httpfromtcp/internal/headers/headerstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewRequest(t *testing.T) {
+	// Test: Known length uses Content-Length
+	r, err := NewRequest("POST", "/submit?x=1", headerstest.Fields("Host", "localhost:42069"), strings.NewReader("hello"))
+	require.NoError(t, err)
+	assert.Equal(t, "5", header(r.Headers, "content-length"))
+	assert.Equal(t, "localhost:42069", r.URL.Host)
+	assert.Equal(t, "x=1", r.URL.RawQuery)
+	var buf bytes.Buffer
+	require.NoError(t, r.Write(&buf))
+	assert.Equal(t, "POST /submit?x=1 HTTP/1.1\r\nHost: localhost:42069\r\nContent-Length: 5\r\n\r\nhello", buf.String())
+
+	// Test: Unknown length is chunked
+	r, err = NewRequest("PUT", "/upload", headerstest.Fields("Host", "localhost"), iotest.OneByteReader(strings.NewReader("abc")))
+	require.NoError(t, err)
+	assert.Equal(t, "chunked", header(r.Headers, "transfer-encoding"))
+	buf.Reset()
+	require.NoError(t, r.Write(&buf))
+	assert.Equal(t, "PUT /upload HTTP/1.1\r\nHost: localhost\r\nTransfer-Encoding: chunked\r\n\r\n1\r\na\r\n1\r\nb\r\n1\r\nc\r\n0\r\n\r\n", buf.String())
+
+	// Test: No body
+	r, err = NewRequest("GET", "/", headerstest.Fields("Host", "localhost"), nil)
+	require.NoError(t, err)
+	buf.Reset()
+	require.NoError(t, r.Write(&buf))
+	assert.Equal(t, "GET / HTTP/1.1\r\nHost: localhost\r\n\r\n", buf.String())
+	r, err = NewRequest("POST", "/", headerstest.Fields("Host", "localhost"), nil)
+	require.NoError(t, err)
+	assert.Equal(t, "0", header(r.Headers, "content-length"))
+
+	// Test: Host comes from an absolute target
+	r, err = NewRequest("GET", "http://example.com/a", nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "example.com", header(r.Headers, "host"))
+
+	// Test: Invalid requests
+	_, err = NewRequest("GET", "/", nil, nil)
+	require.ErrorIs(t, err, ErrMissingHost)
+	_, err = NewRequest("GE T", "/", headerstest.Fields("Host", "localhost"), nil)
+	require.ErrorIs(t, err, ErrInvalidMethod)
+	_, err = NewRequest("GET", "relative", headerstest.Fields("Host", "localhost"), nil)
+	require.ErrorIs(t, err, ErrInvalidTarget)
+
+	// Test: Header values cannot inject lines
+	r, err = NewRequest("GET", "/", headerstest.Fields("Host", "localhost", "X-Bad", "a\r\nInjected: b"), nil)
+	require.NoError(t, err)
+	require.ErrorIs(t, r.Write(io.Discard), ErrInvalidHeaderValue)
+
+	// Test: Body shorter than Content-Length
+	r, err = NewRequest("POST", "/", headerstest.Fields("Host", "localhost", "Content-Length", "10"), strings.NewReader("short"))
+	require.NoError(t, err)
+	require.ErrorIs(t, r.Write(io.Discard), ErrInvalidContentLength)
+}
+
+func TestRequestRoundTrip(t *testing.T) {
+	requests := []string{
+		"GET /coffee HTTP/1.1\r\nHost: localhost:42069\r\nUser-Agent: curl/7.81.0\r\nAccept: */*\r\n\r\n",
+		"POST /submit HTTP/1.1\r\nHost: localhost:42069\r\nContent-Length: 13\r\n\r\nhello world!\n",
+		"POST /submit HTTP/1.1\r\nHost: localhost:42069\r\nTransfer-Encoding: chunked\r\nTrailer: X-Checksum\r\n\r\n5\r\nhello\r\n6\r\n world\r\n0\r\nX-Checksum: abc\r\n\r\n",
+		"OPTIONS * HTTP/1.1\r\nHost: localhost:42069\r\n\r\n",
+		"GET /old HTTP/1.0\r\n\r\n",
+		"GET /tags HTTP/1.1\r\nhost: localhost\r\nX-Tag: one\r\nAccept: */*\r\nx-tag: two, three\r\n\r\n",
+	}
+	for _, data := range requests {
+		first, err := readFullRequest(&chunkReader{data: data, numBytesPerRead: 4})
+		require.NoError(t, err, data)
+
+		var buf bytes.Buffer
+		require.NoError(t, first.Write(&buf))
+		second, err := readFullRequest(&chunkReader{data: buf.String(), numBytesPerRead: 4})
+		require.NoError(t, err, buf.String())
+
+		assert.Equal(t, first.RequestLine, second.RequestLine)
+		assert.Equal(t, first.Headers, second.Headers)
+		assert.Equal(t, first.Body, second.Body)
+		assert.Equal(t, first.Trailers, second.Trailers)
+		if len(first.Body) == 0 && first.Trailers.Len() == 0 {
+			// Field order, casing and repeated lines survive as sent.
+			assert.Equal(t, data, buf.String())
+		}
+
+		// Writing again gives the same bytes.
+		var again bytes.Buffer
+		require.NoError(t, second.Write(&again))
+		assert.Equal(t, buf.String(), again.String())
+	}
+}
@@ -3,7 +3,36 @@ package response
 type StatusCode int
 
 const (
-	HttpOK          StatusCode = 200
-	HttpNotFoud     StatusCode = 400
-	HttpServerError StatusCode = 500
+	HttpOK                          StatusCode = 200
+	HttpPartialContent              StatusCode = 206
+	HttpNotModified                 StatusCode = 304
+	HttpNotFoud                     StatusCode = 400
+	HttpNotAcceptable               StatusCode = 406
+	HttpPreconditionFailed          StatusCode = 412
+	HttpContentTooLarge             StatusCode = 413
+	HttpURITooLong                  StatusCode = 414
+	HttpRangeNotSatisfiable         StatusCode = 416
+	HttpRequestHeaderFieldsTooLarge StatusCode = 431
+	HttpServerError                 StatusCode = 500
+	HttpVersionNotSupported         StatusCode = 505
 )
+
+var statusCodeMap = map[StatusCode]string{
+	HttpOK:                          "OK",
+	HttpPartialContent:              "Partial Content",
+	HttpNotModified:                 "Not Modified",
+	HttpNotFoud:                     "Bad Request",
+	HttpNotAcceptable:               "Not Acceptable",
+	HttpPreconditionFailed:          "Precondition Failed",
+	HttpContentTooLarge:             "Content Too Large",
+	HttpURITooLong:                  "URI Too Long",
+	HttpRangeNotSatisfiable:         "Range Not Satisfiable",
+	HttpRequestHeaderFieldsTooLarge: "Request Header Fields Too Large",
+	HttpServerError:                 "Internal Server Error",
+	HttpVersionNotSupported:         "HTTP Version Not Supported",
+}
+
+// hasBody reports whether a response with this status can carry a body.
+func (s StatusCode) hasBody() bool {
+	return s >= 200 && s != 204 && s != 304
+}
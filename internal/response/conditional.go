@@ -0,0 +1,86 @@
+package response
+
+import (
+	"httpfromtcp/internal/headers"
+	"slices"
+	"strings"
+	"time"
+)
+
+// notModifiedFields are the response fields a 304 keeps, the ones RFC 9110
+// section 15.4.5 lists plus Last-Modified to help caches update.
+var notModifiedFields = []string{"cache-control", "content-location", "date", "etag", "expires", "last-modified", "vary"}
+
+// CheckPreconditions evaluates If-Match, If-Unmodified-Since, If-None-Match
+// and If-Modified-Since from reqHeaders in the order of RFC 9110 section
+// 13.2.2. The current representation is described by the ETag and
+// Last-Modified fields of h; h is nil when there is none, as for a PUT that
+// creates the resource. It returns HttpOK if the request should go ahead, or
+// HttpNotModified or HttpPreconditionFailed.
+func CheckPreconditions(method string, reqHeaders, h *headers.Headers) StatusCode {
+	exists := h != nil
+	etag, _ := h.Get("ETag")
+	lastModified, _ := h.GetTime("Last-Modified")
+	safe := method == "GET" || method == "HEAD"
+
+	if ifMatch, ok := reqHeaders.Get("If-Match"); ok {
+		if !headers.MatchETagList(ifMatch, etag, exists, false) {
+			return HttpPreconditionFailed
+		}
+	} else if !lastModified.IsZero() {
+		if since, err := reqHeaders.GetTime("If-Unmodified-Since"); err == nil && lastModified.Truncate(time.Second).After(since) {
+			return HttpPreconditionFailed
+		}
+	}
+
+	if ifNoneMatch, ok := reqHeaders.Get("If-None-Match"); ok {
+		if headers.MatchETagList(ifNoneMatch, etag, exists, true) {
+			if safe {
+				return HttpNotModified
+			}
+			return HttpPreconditionFailed
+		}
+	} else if safe && !lastModified.IsZero() {
+		if since, err := reqHeaders.GetTime("If-Modified-Since"); err == nil && !lastModified.Truncate(time.Second).After(since) {
+			return HttpNotModified
+		}
+	}
+	return HttpOK
+}
+
+// WriteNotModified writes a complete 304 response. Only the fields of h that
+// a 304 is meant to repeat are sent.
+func (w *Writer) WriteNotModified(h *headers.Headers) error {
+	if err := w.WriteStatusLine(HttpNotModified); err != nil {
+		return err
+	}
+	kept := headers.NewHeaders()
+	for name, value := range h.All() {
+		if slices.Contains(notModifiedFields, strings.ToLower(name)) {
+			kept.Add(name, value)
+		}
+	}
+	return w.WriteHeaders(kept)
+}
+
+// WritePreconditionFailed writes a complete 412 response without a body.
+func (w *Writer) WritePreconditionFailed(h *headers.Headers) error {
+	if err := w.WriteStatusLine(HttpPreconditionFailed); err != nil {
+		return err
+	}
+	h.Set("Content-Length", "0")
+	h.Del("Content-Type")
+	return w.WriteHeaders(h)
+}
+
+// writePrecondition writes the response CheckPreconditions asked for and
+// reports whether it did.
+func (w *Writer) writePrecondition(status StatusCode, h *headers.Headers) (bool, error) {
+	switch status {
+	case HttpNotModified:
+		return true, w.WriteNotModified(h)
+	case HttpPreconditionFailed:
+		return true, w.WritePreconditionFailed(h)
+	}
+	return false, nil
+}
@@ -0,0 +1,91 @@
+package response
+
+import (
+	"bufio"
+	"bytes"
+	"httpfromtcp/internal/headers"
+	"httpfromtcp/internal/headers/headerstest"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckPreconditions(t *testing.T) {
+	h := headerstest.Fields("etag", `"v2"`, "last-modified", "Fri, 01 Mar 2024 12:30:00 GMT")
+	const before = "Thu, 29 Feb 2024 00:00:00 GMT"
+	const same = "Fri, 01 Mar 2024 12:30:00 GMT"
+	const after = "Sat, 02 Mar 2024 00:00:00 GMT"
+
+	tests := []struct {
+		name    string
+		method  string
+		request *headers.Headers
+		want    StatusCode
+	}{
+		{"no conditions", "GET", headerstest.Fields(), HttpOK},
+		{"If-None-Match hit", "GET", headerstest.Fields("if-none-match", `"v1", W/"v2"`), HttpNotModified},
+		{"If-None-Match miss", "GET", headerstest.Fields("if-none-match", `"v1"`), HttpOK},
+		{"If-None-Match star", "HEAD", headerstest.Fields("if-none-match", "*"), HttpNotModified},
+		{"If-None-Match on POST", "POST", headerstest.Fields("if-none-match", `"v2"`), HttpPreconditionFailed},
+		{"If-Modified-Since same", "GET", headerstest.Fields("if-modified-since", same), HttpNotModified},
+		{"If-Modified-Since after", "GET", headerstest.Fields("if-modified-since", after), HttpNotModified},
+		{"If-Modified-Since before", "GET", headerstest.Fields("if-modified-since", before), HttpOK},
+		{"If-Modified-Since invalid", "GET", headerstest.Fields("if-modified-since", "soon"), HttpOK},
+		{"If-Modified-Since on POST", "POST", headerstest.Fields("if-modified-since", same), HttpOK},
+		{"If-None-Match wins over If-Modified-Since", "GET", headerstest.Fields("if-none-match", `"v1"`, "if-modified-since", after), HttpOK},
+		{"If-Match hit", "PUT", headerstest.Fields("if-match", `"v2"`), HttpOK},
+		{"If-Match weak", "PUT", headerstest.Fields("if-match", `W/"v2"`), HttpPreconditionFailed},
+		{"If-Match miss", "PUT", headerstest.Fields("if-match", `"v1"`), HttpPreconditionFailed},
+		{"If-Unmodified-Since before", "PUT", headerstest.Fields("if-unmodified-since", before), HttpPreconditionFailed},
+		{"If-Unmodified-Since same", "PUT", headerstest.Fields("if-unmodified-since", same), HttpOK},
+		{"If-Match wins over If-Unmodified-Since", "PUT", headerstest.Fields("if-match", `"v2"`, "if-unmodified-since", before), HttpOK},
+		{"If-Match checked before If-None-Match", "GET", headerstest.Fields("if-match", `"v1"`, "if-none-match", `"v2"`), HttpPreconditionFailed},
+	}
+	for _, tc := range tests {
+		assert.Equal(t, tc.want, CheckPreconditions(tc.method, tc.request, h), tc.name)
+	}
+
+	// Test: Without validators only "*" conditions can be decided, and "*"
+	// only matches when there is a representation
+	assert.Equal(t, HttpOK, CheckPreconditions("GET", headerstest.Fields("if-modified-since", after), headerstest.Fields()))
+	assert.Equal(t, HttpPreconditionFailed, CheckPreconditions("PUT", headerstest.Fields("if-match", "*"), nil))
+	assert.Equal(t, HttpOK, CheckPreconditions("PUT", headerstest.Fields("if-none-match", "*"), nil))
+
+	// Test: "*" matches a representation that has no ETag
+	assert.Equal(t, HttpOK, CheckPreconditions("PUT", headerstest.Fields("if-match", "*"), headerstest.Fields()))
+	assert.Equal(t, HttpNotModified, CheckPreconditions("GET", headerstest.Fields("if-none-match", "*"), headerstest.Fields()))
+	assert.Equal(t, HttpPreconditionFailed, CheckPreconditions("PUT", headerstest.Fields("if-none-match", "*"), headerstest.Fields()))
+}
+
+func TestWriteNotModified(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	h := headerstest.Fields("etag", `"v2"`, "cache-control", "max-age=60", "content-type", "video/mp4", "content-length", "1000")
+	require.NoError(t, w.WriteNotModified(h))
+	require.NoError(t, w.Finish())
+	assert.True(t, w.KeepAlive())
+
+	resp, err := http.ReadResponse(bufio.NewReader(&buf), nil)
+	require.NoError(t, err)
+	assert.Equal(t, 304, resp.StatusCode)
+	assert.Equal(t, `"v2"`, resp.Header.Get("ETag"))
+	assert.Equal(t, "max-age=60", resp.Header.Get("Cache-Control"))
+	assert.Empty(t, resp.Header.Get("Content-Type"))
+	assert.Empty(t, resp.Header.Get("Content-Length"))
+	assert.Empty(t, resp.Header.Get("Connection"))
+}
+
+func TestServeContentConditional(t *testing.T) {
+	resp := serve(t, headerstest.Fields("if-none-match", `"v1"`, "range", "bytes=0-1"))
+	assert.Equal(t, 304, resp.StatusCode)
+	assert.Equal(t, "", readBody(t, resp))
+
+	resp = serveMethod(t, "PUT", headerstest.Fields("if-match", `"v0"`))
+	assert.Equal(t, 412, resp.StatusCode)
+
+	resp = serve(t, headerstest.Fields("if-modified-since", "Thu, 29 Feb 2024 00:00:00 GMT"))
+	assert.Equal(t, 200, resp.StatusCode)
+	assert.Equal(t, rangeContent, readBody(t, resp))
+}
@@ -0,0 +1,155 @@
+package response
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"httpfromtcp/internal/headers"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ServeContent answers a request for content, size bytes long. It evaluates
+// the conditional headers in reqHeaders first and may answer 304 or 412;
+// otherwise it sends all of content or the parts the Range header asks for
+// with 200, 206 or 416. h holds the response headers; its ETag and
+// Last-Modified are the validators the conditions are checked against.
+// Range is only honoured for GET, and invalid Range headers are ignored, as
+// RFC 9110 requires. HEAD gets the headers of the full response and no body.
+func (w *Writer) ServeContent(method string, reqHeaders, h *headers.Headers, content io.ReaderAt, size int64) error {
+	h.Set("Accept-Ranges", "bytes")
+	if done, err := w.writePrecondition(CheckPreconditions(method, reqHeaders, h), h); done {
+		return err
+	}
+	if method == "GET" {
+		ranges, err := requestedRanges(reqHeaders, h, size)
+		if errors.Is(err, headers.ErrUnsatisfiableRange) {
+			return w.WriteRangeNotSatisfiable(h, size)
+		}
+		if len(ranges) > 0 {
+			return w.WritePartialContent(h, content, size, ranges)
+		}
+	}
+
+	if err := w.WriteStatusLine(HttpOK); err != nil {
+		return err
+	}
+	h.Set("Content-Length", strconv.FormatInt(size, 10))
+	if err := w.WriteHeaders(h); err != nil {
+		return err
+	}
+	if method == "HEAD" {
+		return nil
+	}
+	return w.copyBody(io.NewSectionReader(content, 0, size))
+}
+
+// requestedRanges returns the ranges to serve, or none if the full content
+// should be sent instead.
+func requestedRanges(reqHeaders, h *headers.Headers, size int64) ([]headers.ByteRange, error) {
+	rangeHeader, ok := reqHeaders.Get("Range")
+	if !ok {
+		return nil, nil
+	}
+	if ifRange, ok := reqHeaders.Get("If-Range"); ok {
+		etag, _ := h.Get("ETag")
+		lastModified, _ := h.GetTime("Last-Modified")
+		if !headers.IfRangeMatches(ifRange, etag, lastModified) {
+			return nil, nil
+		}
+	}
+	specs, err := headers.ParseRange(rangeHeader)
+	if err != nil {
+		return nil, nil
+	}
+	ranges, err := headers.ResolveRanges(specs, size)
+	if err != nil {
+		return nil, err
+	}
+	// Overlapping ranges that add up to more than the whole content are
+	// cheaper to answer with the whole content.
+	var total int64
+	for _, r := range ranges {
+		total += r.Length
+	}
+	if total > size {
+		return nil, nil
+	}
+	return ranges, nil
+}
+
+// WritePartialContent writes a complete 206 response. A single range is sent
+// as is with Content-Range; several are sent as multipart/byteranges, each
+// part carrying the Content-Type from h.
+func (w *Writer) WritePartialContent(h *headers.Headers, content io.ReaderAt, size int64, ranges []headers.ByteRange) error {
+	if len(ranges) == 0 {
+		return fmt.Errorf("no ranges to write")
+	}
+	if err := w.WriteStatusLine(HttpPartialContent); err != nil {
+		return err
+	}
+	if len(ranges) == 1 {
+		r := ranges[0]
+		h.Set("Content-Range", r.ContentRange(size))
+		h.Set("Content-Length", strconv.FormatInt(r.Length, 10))
+		if err := w.WriteHeaders(h); err != nil {
+			return err
+		}
+		return w.copyBody(io.NewSectionReader(content, r.Start, r.Length))
+	}
+
+	boundary, err := randomBoundary()
+	if err != nil {
+		return err
+	}
+	contentType, _ := h.Get("Content-Type")
+	partHeaders := make([]string, len(ranges))
+	length := int64(len("--" + boundary + "--" + crlf))
+	for i, r := range ranges {
+		partHeader := "--" + boundary + crlf
+		if contentType != "" {
+			partHeader += "Content-Type: " + contentType + crlf
+		}
+		partHeader += "Content-Range: " + r.ContentRange(size) + crlf + crlf
+		partHeaders[i] = partHeader
+		length += int64(len(partHeader)) + r.Length + int64(len(crlf))
+	}
+	h.Set("Content-Type", "multipart/byteranges; boundary="+boundary)
+	h.Set("Content-Length", strconv.FormatInt(length, 10))
+	if err := w.WriteHeaders(h); err != nil {
+		return err
+	}
+
+	readers := make([]io.Reader, 0, 3*len(ranges)+1)
+	for i, r := range ranges {
+		readers = append(readers,
+			strings.NewReader(partHeaders[i]),
+			io.NewSectionReader(content, r.Start, r.Length),
+			strings.NewReader(crlf),
+		)
+	}
+	readers = append(readers, strings.NewReader("--"+boundary+"--"+crlf))
+	return w.copyBody(io.MultiReader(readers...))
+}
+
+// WriteRangeNotSatisfiable writes a complete 416 response telling the client
+// how long the content really is.
+func (w *Writer) WriteRangeNotSatisfiable(h *headers.Headers, size int64) error {
+	if err := w.WriteStatusLine(HttpRangeNotSatisfiable); err != nil {
+		return err
+	}
+	h.Set("Content-Range", fmt.Sprintf("bytes */%d", size))
+	h.Set("Content-Length", "0")
+	h.Del("Content-Type")
+	return w.WriteHeaders(h)
+}
+
+func randomBoundary() (string, error) {
+	var buf [16]byte
+	if _, err := rand.Read(buf[:]); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf[:]), nil
+}
@@ -0,0 +1,129 @@
+package response
+
+import (
+	"bufio"
+	"bytes"
+	"httpfromtcp/internal/headers"
+	"httpfromtcp/internal/headers/headerstest"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const rangeContent = "0123456789abcdefghijklmnopqrstuvwxyz"
+
+func serve(t *testing.T, reqHeaders *headers.Headers) *http.Response {
+	return serveMethod(t, "GET", reqHeaders)
+}
+
+func serveMethod(t *testing.T, method string, reqHeaders *headers.Headers) *http.Response {
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	w.SetMethod(method)
+	h := headers.NewHeaders()
+	h.Set("Content-Type", "text/plain")
+	h.Set("ETag", `"v1"`)
+	h.Set("Last-Modified", "Fri, 01 Mar 2024 12:30:00 GMT")
+	content := strings.NewReader(rangeContent)
+	require.NoError(t, w.ServeContent(method, reqHeaders, h, content, int64(len(rangeContent))))
+	require.NoError(t, w.Finish())
+	assert.True(t, w.KeepAlive())
+
+	resp, err := http.ReadResponse(bufio.NewReader(&buf), &http.Request{Method: method})
+	require.NoError(t, err)
+	return resp
+}
+
+func readBody(t *testing.T, resp *http.Response) string {
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return string(body)
+}
+
+func TestServeContent(t *testing.T) {
+	// Test: No Range gets everything
+	resp := serve(t, headerstest.Fields())
+	assert.Equal(t, 200, resp.StatusCode)
+	assert.Equal(t, "bytes", resp.Header.Get("Accept-Ranges"))
+	assert.Equal(t, rangeContent, readBody(t, resp))
+
+	// Test: Single ranges
+	for value, want := range map[string]string{
+		"bytes=0-9":   "0123456789",
+		"bytes=30-":   "uvwxyz",
+		"bytes=-3":    "xyz",
+		"bytes=10-10": "a",
+		"bytes=34-99": "yz",
+	} {
+		resp = serve(t, headerstest.Fields("range", value))
+		assert.Equal(t, 206, resp.StatusCode, value)
+		assert.Equal(t, want, readBody(t, resp), value)
+	}
+	resp = serve(t, headerstest.Fields("range", "bytes=0-9"))
+	assert.Equal(t, "bytes 0-9/36", resp.Header.Get("Content-Range"))
+	assert.Equal(t, "text/plain", resp.Header.Get("Content-Type"))
+
+	// Test: Several ranges are multipart/byteranges
+	resp = serve(t, headerstest.Fields("range", "bytes=0-1, 10-12, -2"))
+	assert.Equal(t, 206, resp.StatusCode)
+	mediaType, params, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	require.NoError(t, err)
+	assert.Equal(t, "multipart/byteranges", mediaType)
+	mr := multipart.NewReader(resp.Body, params["boundary"])
+	wantParts := []struct{ contentRange, body string }{
+		{"bytes 0-1/36", "01"},
+		{"bytes 10-12/36", "abc"},
+		{"bytes 34-35/36", "yz"},
+	}
+	for _, want := range wantParts {
+		part, err := mr.NextPart()
+		require.NoError(t, err)
+		assert.Equal(t, want.contentRange, part.Header.Get("Content-Range"))
+		assert.Equal(t, "text/plain", part.Header.Get("Content-Type"))
+		body, err := io.ReadAll(part)
+		require.NoError(t, err)
+		assert.Equal(t, want.body, string(body))
+	}
+	_, err = mr.NextPart()
+	assert.Equal(t, io.EOF, err)
+
+	// Test: Unsatisfiable
+	resp = serve(t, headerstest.Fields("range", "bytes=100-"))
+	assert.Equal(t, 416, resp.StatusCode)
+	assert.Equal(t, "bytes */36", resp.Header.Get("Content-Range"))
+	assert.Equal(t, "", readBody(t, resp))
+
+	// Test: Invalid or wasteful Range headers are ignored
+	for _, value := range []string{"bytes=9-1", "items=0-1", "bytes=0-35,0-35"} {
+		resp = serve(t, headerstest.Fields("range", value))
+		assert.Equal(t, 200, resp.StatusCode, value)
+		assert.Equal(t, rangeContent, readBody(t, resp), value)
+	}
+
+	// Test: If-Range
+	resp = serve(t, headerstest.Fields("range", "bytes=0-1", "if-range", `"v1"`))
+	assert.Equal(t, 206, resp.StatusCode)
+	resp = serve(t, headerstest.Fields("range", "bytes=0-1", "if-range", "Fri, 01 Mar 2024 12:30:00 GMT"))
+	assert.Equal(t, 206, resp.StatusCode)
+	resp = serve(t, headerstest.Fields("range", "bytes=0-1", "if-range", `"v0"`))
+	assert.Equal(t, 200, resp.StatusCode)
+	assert.Equal(t, rangeContent, readBody(t, resp))
+
+	// Test: HEAD gets the full length and no body, whatever the Range
+	resp = serveMethod(t, "HEAD", headerstest.Fields("range", "bytes=0-1"))
+	assert.Equal(t, 200, resp.StatusCode)
+	assert.Equal(t, int64(len(rangeContent)), resp.ContentLength)
+	assert.Empty(t, resp.Header.Get("Content-Range"))
+	assert.Equal(t, "", readBody(t, resp))
+
+	// Test: Range is ignored for other methods
+	resp = serveMethod(t, "POST", headerstest.Fields("range", "bytes=0-1"))
+	assert.Equal(t, 200, resp.StatusCode)
+	assert.Equal(t, rangeContent, readBody(t, resp))
+}
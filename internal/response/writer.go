@@ -4,11 +4,25 @@ import (
 	"fmt"
 	"httpfromtcp/internal/headers"
 	"io"
+	"net/http"
+	"strings"
 )
 
 type Writer struct {
-	state writerState
-	w     io.Writer
+	state           writerState
+	w               io.Writer
+	method          string
+	status          StatusCode
+	major           int
+	minor           int
+	keepAlive       bool
+	chunked         bool
+	unchunked       bool
+	noBody          bool
+	contentLength   int64
+	bodyWritten     int64
+	trailersPending bool
+	canonical       bool
 }
 
 type writerState string
@@ -18,41 +32,121 @@ const (
 	stateWriteHeaders  writerState = "Write Headers"
 	stateWriteBody     writerState = "Write Body"
 	stateWriteTrailers writerState = "Write Trailers"
+	stateDone          writerState = "Done"
 )
 
 func NewWriter(w io.Writer) *Writer {
 	return &Writer{
-		state: stateStatusLine,
-		w:     w,
+		state:         stateStatusLine,
+		w:             w,
+		major:         1,
+		minor:         1,
+		keepAlive:     true,
+		contentLength: -1,
 	}
 }
 
+// SetVersion sets the HTTP version of the status line, normally the version
+// of the request being answered. HTTP/1.0 responses cannot be chunked, so
+// chunked writes go out as a plain body that ends when the connection closes.
+func (w *Writer) SetVersion(major, minor int) {
+	w.major = major
+	w.minor = minor
+}
+
+// SetMethod sets the method of the request being answered. A response to
+// HEAD gets the same header fields as GET, Content-Length included, but body
+// writes are dropped so the connection stays in step with the client.
+func (w *Writer) SetMethod(method string) {
+	w.method = method
+}
+
+func (w *Writer) isHTTP10() bool {
+	return w.major == 1 && w.minor == 0
+}
+
+// SetKeepAlive controls whether the response offers to keep the connection
+// open. It has to be called before WriteHeaders.
+func (w *Writer) SetKeepAlive(keepAlive bool) {
+	w.keepAlive = keepAlive
+}
+
+// SetCanonicalNames makes the writer send field names in canonical form,
+// "content-type" as "Content-Type", instead of as the handler gave them.
+func (w *Writer) SetCanonicalNames(canonical bool) {
+	w.canonical = canonical
+}
+
+// KeepAlive reports whether the connection can carry another request once
+// this response is finished.
+func (w *Writer) KeepAlive() bool {
+	return w.keepAlive
+}
+
 func (w *Writer) WriteStatusLine(statusCode StatusCode) error {
 	if w.state != stateStatusLine {
 		return fmt.Errorf("Called write functions out of order want %s got %s", stateStatusLine, w.state)
 	}
 	defer func() { w.state = stateWriteHeaders }()
-	var statusCodeMap = map[StatusCode]string{
-		HttpOK:          "OK",
-		HttpNotFoud:     "Bad Request",
-		HttpServerError: "Internal Server Error",
+	w.status = statusCode
+	statusInfo, ok := statusCodeMap[statusCode]
+	if !ok {
+		statusInfo = http.StatusText(int(statusCode))
 	}
-	statusInfo, _ := statusCodeMap[statusCode]
-	_, err := fmt.Fprintf(w.w, "HTTP/1.1 %d %s", statusCode, statusInfo+crlf)
+	_, err := fmt.Fprintf(w.w, "HTTP/%d.%d %d %s", w.major, w.minor, statusCode, statusInfo+crlf)
 	return err
 }
 
-func (w *Writer) WriteHeaders(headers headers.Headers) error {
+func (w *Writer) WriteHeaders(h *headers.Headers) error {
 	if w.state != stateWriteHeaders {
 		return fmt.Errorf("Called write functions out of order want %s got %s", stateWriteHeaders, w.state)
 	}
 	defer func() { w.state = stateWriteBody }()
-	var headerWrite string
-	for key, header := range headers {
-		headerWrite += key + ": " + header + crlf
+	if connection, ok := h.Get("Connection"); ok && hasToken(connection, "close") {
+		w.keepAlive = false
 	}
-	headerWrite += crlf
-	_, err := w.w.Write([]byte(headerWrite))
+	if transferEncoding, ok := h.Get("Transfer-Encoding"); ok {
+		w.chunked = hasToken(transferEncoding, "chunked")
+	}
+	if n, err := h.GetInt64("Content-Length"); err == nil {
+		w.contentLength = n
+	}
+	if w.chunked && w.isHTTP10() {
+		w.chunked = false
+		w.unchunked = true
+	}
+	if w.method == "HEAD" || !w.status.hasBody() {
+		w.noBody = true
+		w.chunked = false
+		w.contentLength = 0
+	}
+	if !w.chunked && w.contentLength < 0 {
+		w.keepAlive = false
+	}
+
+	connection := ""
+	if !w.keepAlive {
+		connection = "close"
+	} else if w.isHTTP10() {
+		connection = "keep-alive"
+	}
+
+	var exclude []string
+	if connection != "" {
+		exclude = append(exclude, "Connection")
+	}
+	if w.unchunked {
+		exclude = append(exclude, "Transfer-Encoding", "Trailer")
+	}
+	buf, err := h.AppendFields(make([]byte, 0, 256), headers.WriteOptions{Canonical: w.canonical, Exclude: exclude})
+	if err != nil {
+		w.keepAlive = false
+		return err
+	}
+	if connection != "" {
+		buf = append(buf, "Connection: "+connection+crlf...)
+	}
+	_, err = w.w.Write(append(buf, crlf...))
 	return err
 }
 
@@ -61,14 +155,43 @@ func (w *Writer) WriteBody(p []byte) (int, error) {
 		return 0, fmt.Errorf("Called write functions out of order want %s got %s", stateWriteBody, w.state)
 	}
 	defer func() { w.state = stateWriteTrailers }()
+	if w.noBody {
+		return len(p), nil
+	}
 
-	return w.w.Write(p)
+	n, err := w.w.Write(p)
+	w.bodyWritten += int64(n)
+	return n, err
+}
+
+// copyBody writes all of src as the body, for bodies too large to pass to
+// WriteBody in one piece.
+func (w *Writer) copyBody(src io.Reader) error {
+	if w.state != stateWriteBody {
+		return fmt.Errorf("Called write functions out of order want %s got %s", stateWriteBody, w.state)
+	}
+	defer func() { w.state = stateWriteTrailers }()
+	if w.noBody {
+		return nil
+	}
+
+	n, err := io.Copy(w.w, src)
+	w.bodyWritten += n
+	return err
 }
 
 func (w *Writer) WriteChunkedBody(p []byte) (int, error) {
 	if w.state != stateWriteBody {
 		return 0, fmt.Errorf("Called write functions out of order want %s got %s", stateWriteBody, w.state)
 	}
+	if w.noBody {
+		return len(p), nil
+	}
+	if w.unchunked {
+		n, err := w.w.Write(p)
+		w.bodyWritten += int64(n)
+		return n, err
+	}
 
 	chunkSize := len(p)
 
@@ -98,18 +221,56 @@ func (w *Writer) WriteChunkedBodyEnd() (int, error) {
 		return 0, fmt.Errorf("Called write functions out of order want %s got %s", stateWriteBody, w.state)
 	}
 	defer func() { w.state = stateWriteTrailers }()
+	if w.unchunked || w.noBody {
+		return 0, nil
+	}
+	w.trailersPending = true
 	return w.w.Write([]byte("0\r\n"))
 }
 
-func (w *Writer) WriteTrailers(traiers headers.Headers) error {
+func (w *Writer) WriteTrailers(traiers *headers.Headers) error {
 	if w.state != stateWriteTrailers {
 		return fmt.Errorf("Called write functions out of order want %s got %s", stateWriteTrailers, w.state)
 	}
-	var headerWrite string
-	for key, header := range traiers {
-		headerWrite += key + ": " + header + crlf
+	defer func() { w.state = stateDone }()
+	w.trailersPending = false
+	if w.unchunked || w.noBody {
+		return nil
 	}
-	headerWrite += crlf
-	_, err := w.w.Write([]byte(headerWrite))
-	return err
+	if err := traiers.Write(w.w, headers.WriteOptions{Canonical: w.canonical}); err != nil {
+		w.keepAlive = false
+		return err
+	}
+	return nil
+}
+
+// Finish ends a chunked response whose trailers were never written, and turns
+// keep-alive off when the handler left the message incomplete.
+func (w *Writer) Finish() error {
+	switch w.state {
+	case stateDone:
+	case stateWriteTrailers:
+		if w.trailersPending {
+			return w.WriteTrailers(headers.NewHeaders())
+		}
+		if w.bodyWritten != w.contentLength {
+			w.keepAlive = false
+		}
+	case stateWriteBody:
+		if w.chunked || w.contentLength != 0 {
+			w.keepAlive = false
+		}
+	default:
+		w.keepAlive = false
+	}
+	return nil
+}
+
+func hasToken(value, token string) bool {
+	for _, part := range strings.Split(value, ",") {
+		if strings.EqualFold(strings.TrimSpace(part), token) {
+			return true
+		}
+	}
+	return false
 }
@@ -0,0 +1,119 @@
+package response
+
+import (
+	"bytes"
+	"httpfromtcp/internal/headers"
+	"httpfromtcp/internal/headers/headerstest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteHeadersFieldLines(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	h := headerstest.Fields(
+		"Content-Type", "text/plain",
+		"Set-Cookie", "a=1; Expires=Fri, 01 Mar 2024 12:30:00 GMT",
+		"x-request-id", "42",
+		"Set-Cookie", "b=2",
+		"Transfer-Encoding", "chunked",
+		"Trailer", "X-Checksum",
+	)
+	require.NoError(t, w.WriteStatusLine(HttpOK))
+	require.NoError(t, w.WriteHeaders(h))
+	_, err := w.WriteChunkedBody([]byte("hi"))
+	require.NoError(t, err)
+	_, err = w.WriteChunkedBodyEnd()
+	require.NoError(t, err)
+	require.NoError(t, w.WriteTrailers(headerstest.Fields("X-Checksum", "abc", "x-checksum", "def")))
+
+	assert.Equal(t, "HTTP/1.1 200 OK\r\n"+
+		"Content-Type: text/plain\r\n"+
+		"Set-Cookie: a=1; Expires=Fri, 01 Mar 2024 12:30:00 GMT\r\n"+
+		"x-request-id: 42\r\n"+
+		"Set-Cookie: b=2\r\n"+
+		"Transfer-Encoding: chunked\r\n"+
+		"Trailer: X-Checksum\r\n"+
+		"\r\n"+
+		"2\r\nhi\r\n0\r\n"+
+		"X-Checksum: abc\r\n"+
+		"x-checksum: def\r\n"+
+		"\r\n", buf.String())
+}
+
+func TestWriteHeadersCanonical(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	w.SetVersion(1, 0)
+	w.SetCanonicalNames(true)
+	require.NoError(t, w.WriteStatusLine(HttpOK))
+	require.NoError(t, w.WriteHeaders(headerstest.Fields("content-type", "text/plain", "content-length", "2", "connection", "keep-alive")))
+	_, err := w.WriteBody([]byte("hi"))
+	require.NoError(t, err)
+	assert.Equal(t, "HTTP/1.0 200 OK\r\nContent-Type: text/plain\r\nContent-Length: 2\r\nConnection: keep-alive\r\n\r\nhi", buf.String())
+}
+
+func TestWriteStatusLineReason(t *testing.T) {
+	for code, want := range map[StatusCode]string{
+		HttpNotFoud: "HTTP/1.1 400 Bad Request\r\n",
+		201:         "HTTP/1.1 201 Created\r\n",
+		404:         "HTTP/1.1 404 Not Found\r\n",
+		599:         "HTTP/1.1 599 \r\n",
+	} {
+		var buf bytes.Buffer
+		require.NoError(t, NewWriter(&buf).WriteStatusLine(code))
+		assert.Equal(t, want, buf.String())
+	}
+}
+
+func TestWriteHead(t *testing.T) {
+	// Test: HEAD keeps Content-Length but drops the body
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	w.SetMethod("HEAD")
+	require.NoError(t, w.WriteStatusLine(HttpOK))
+	require.NoError(t, w.WriteHeaders(headerstest.Fields("Content-Length", "5")))
+	n, err := w.WriteBody([]byte("hello"))
+	require.NoError(t, err)
+	assert.Equal(t, 5, n)
+	require.NoError(t, w.Finish())
+	assert.Equal(t, "HTTP/1.1 200 OK\r\nContent-Length: 5\r\n\r\n", buf.String())
+	assert.True(t, w.KeepAlive())
+
+	// Test: HEAD drops chunks, the last chunk and trailers
+	buf.Reset()
+	w = NewWriter(&buf)
+	w.SetMethod("HEAD")
+	require.NoError(t, w.WriteStatusLine(HttpOK))
+	require.NoError(t, w.WriteHeaders(headerstest.Fields("Transfer-Encoding", "chunked", "Trailer", "X-Checksum")))
+	_, err = w.WriteChunkedBody([]byte("hi"))
+	require.NoError(t, err)
+	_, err = w.WriteChunkedBodyEnd()
+	require.NoError(t, err)
+	require.NoError(t, w.WriteTrailers(headerstest.Fields("X-Checksum", "abc")))
+	require.NoError(t, w.Finish())
+	assert.Equal(t, "HTTP/1.1 200 OK\r\nTransfer-Encoding: chunked\r\nTrailer: X-Checksum\r\n\r\n", buf.String())
+	assert.True(t, w.KeepAlive())
+
+	// Test: A HEAD handler that never writes the body keeps the connection
+	buf.Reset()
+	w = NewWriter(&buf)
+	w.SetMethod("HEAD")
+	require.NoError(t, w.WriteStatusLine(HttpOK))
+	require.NoError(t, w.WriteHeaders(headerstest.Fields("Content-Length", "5")))
+	require.NoError(t, w.Finish())
+	assert.True(t, w.KeepAlive())
+}
+
+func TestWriteHeadersRejectsInjection(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	require.NoError(t, w.WriteStatusLine(HttpOK))
+	buf.Reset()
+	err := w.WriteHeaders(headerstest.Fields("Content-Length", "0", "Location", "/a\r\n\r\nHTTP/1.1 200 OK"))
+	require.ErrorIs(t, err, headers.ErrInvalidHeaderValue)
+	assert.Empty(t, buf.String())
+	assert.False(t, w.KeepAlive())
+}
@@ -0,0 +1,120 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+var (
+	ErrClientDisconnected = errors.New("client disconnected")
+	ErrServerClosed       = errors.New("server closed")
+)
+
+// aLongTimeAgo is a read deadline that unblocks a pending Read at once.
+var aLongTimeAgo = time.Unix(1, 0)
+
+// connReader is what the request Reader reads from. While a handler runs and
+// the request body has been read in full, it keeps a one-byte read pending on
+// the connection so that a client hanging up cancels the request context. A
+// byte that arrives instead, the start of a pipelined request, is kept for
+// the next Read.
+type connReader struct {
+	conn net.Conn
+
+	mu      sync.Mutex
+	cond    *sync.Cond
+	inRead  bool
+	aborted bool
+	hasByte bool
+	byteBuf [1]byte
+	cancel  context.CancelCauseFunc
+}
+
+func newConnReader(conn net.Conn) *connReader {
+	cr := &connReader{conn: conn}
+	cr.cond = sync.NewCond(&cr.mu)
+	return cr
+}
+
+func (cr *connReader) Read(p []byte) (int, error) {
+	cr.mu.Lock()
+	if cr.inRead {
+		cr.mu.Unlock()
+		return 0, errors.New("concurrent read while a background read is pending")
+	}
+	if cr.hasByte && len(p) > 0 {
+		p[0] = cr.byteBuf[0]
+		cr.hasByte = false
+		cr.mu.Unlock()
+		return 1, nil
+	}
+	cr.mu.Unlock()
+	return cr.conn.Read(p)
+}
+
+// startBackgroundRead watches the connection for the client going away and
+// calls cancel if it does.
+func (cr *connReader) startBackgroundRead(cancel context.CancelCauseFunc) {
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+	if cr.inRead || cr.hasByte {
+		return
+	}
+	cr.inRead = true
+	cr.cancel = cancel
+	cr.conn.SetReadDeadline(time.Time{})
+	go cr.backgroundRead()
+}
+
+func (cr *connReader) backgroundRead() {
+	n, err := cr.conn.Read(cr.byteBuf[:])
+	cr.mu.Lock()
+	if n == 1 {
+		cr.hasByte = true
+	}
+	var netErr net.Error
+	if err != nil && !(cr.aborted && errors.As(err, &netErr) && netErr.Timeout()) {
+		cr.cancel(ErrClientDisconnected)
+	}
+	cr.aborted = false
+	cr.inRead = false
+	cr.cancel = nil
+	cr.mu.Unlock()
+	cr.cond.Broadcast()
+}
+
+// abortPendingRead stops a background read and waits for it to finish, so
+// the connection can be read normally again.
+func (cr *connReader) abortPendingRead() {
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+	if !cr.inRead {
+		return
+	}
+	cr.aborted = true
+	cr.conn.SetReadDeadline(aLongTimeAgo)
+	for cr.inRead {
+		cr.cond.Wait()
+	}
+	cr.conn.SetReadDeadline(time.Time{})
+}
+
+// bodyEOFSignal starts the background read once the handler has read the
+// request body to the end.
+type bodyEOFSignal struct {
+	io.ReadCloser
+	onEOF func()
+}
+
+func (b *bodyEOFSignal) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if errors.Is(err, io.EOF) && b.onEOF != nil {
+		b.onEOF()
+		b.onEOF = nil
+	}
+	return n, err
+}
@@ -1,37 +1,79 @@
 package server
 
 import (
+	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
 	"httpfromtcp/internal/request"
 	"httpfromtcp/internal/response"
 	"log"
 	"net"
 	"sync/atomic"
+	"time"
 )
 
+// maxDiscardBytes is how much unread request body the server will skip to
+// keep a connection open; anything larger closes the connection instead.
+const maxDiscardBytes = 256 << 10
+
 type Handler func(w *response.Writer, req *request.Request)
 
+type Config struct {
+	Parser      request.ParserConfig
+	IdleTimeout time.Duration
+	// RequestTimeout is how long a handler may run before the request
+	// context is cancelled. Zero means no limit.
+	RequestTimeout time.Duration
+	// TLSConfig, if set, makes the server accept TLS connections only. It
+	// needs at least one certificate or a GetCertificate callback.
+	TLSConfig *tls.Config
+}
+
 type Server struct {
 	listener net.Listener
 	closed   atomic.Bool
 	handler  Handler
+	config   Config
+
+	// baseCtx is the parent of every request context and is cancelled by
+	// Close.
+	baseCtx    context.Context
+	cancelBase context.CancelCauseFunc
+}
+
+func DefaultConfig() Config {
+	return Config{
+		Parser:      request.DefaultParserConfig(),
+		IdleTimeout: 30 * time.Second,
+	}
 }
 
 func Serve(port int, h Handler) (*Server, error) {
+	return ServeWithConfig(port, h, DefaultConfig())
+}
+
+func ServeWithConfig(port int, h Handler, config Config) (*Server, error) {
 	l, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
 	if err != nil {
 		return nil, err
 	}
+	if config.TLSConfig != nil {
+		l = tls.NewListener(l, config.TLSConfig)
+	}
 	s := &Server{
 		listener: l,
 		handler:  h,
+		config:   config,
 	}
+	s.baseCtx, s.cancelBase = context.WithCancelCause(context.Background())
 	go s.listen()
 	return s, nil
 }
 
 func (s *Server) Close() error {
 	s.closed.Store(true)
+	s.cancelBase(ErrServerClosed)
 	if s.listener != nil {
 		return s.listener.Close()
 	}
@@ -48,18 +90,115 @@ func (s *Server) listen() {
 			log.Printf("Error accepting connection: %v", err)
 			continue
 		}
-		go s.handle(conn)
+		go s.handle(conn, time.Now())
 	}
 }
 
-func (s *Server) handle(conn net.Conn) {
+func (s *Server) handle(conn net.Conn, acceptedAt time.Time) {
 	defer conn.Close()
-	w := response.NewWriter(conn)
+	var tlsState *tls.ConnectionState
+	if tlsConn, ok := conn.(*tls.Conn); ok {
+		if s.config.IdleTimeout > 0 {
+			conn.SetDeadline(time.Now().Add(s.config.IdleTimeout))
+		}
+		if err := tlsConn.Handshake(); err != nil {
+			return
+		}
+		conn.SetDeadline(time.Time{})
+		state := tlsConn.ConnectionState()
+		tlsState = &state
+	}
+	cr := newConnReader(conn)
+	reader := request.NewReader(cr, s.config.Parser)
+	defer reader.Release()
 
-	request, err := request.RequestFromReader(conn)
-	if err != nil {
-		w.WriteStatusLine(response.HttpNotFoud)
-		return
+	for sequence := 1; ; sequence++ {
+		if s.config.IdleTimeout > 0 {
+			conn.SetReadDeadline(time.Now().Add(s.config.IdleTimeout))
+		}
+		req, err := reader.ReadRequest()
+		if err != nil {
+			var parseErr *request.ParseError
+			if errors.As(err, &parseErr) {
+				writeError(response.NewWriter(conn), statusForError(err))
+			}
+			return
+		}
+		conn.SetReadDeadline(time.Time{})
+
+		req.RemoteAddr = conn.RemoteAddr().String()
+		req.LocalAddr = conn.LocalAddr().String()
+		req.AcceptedAt = acceptedAt
+		req.Sequence = sequence
+		req.TLS = tlsState
+
+		ctx, cancel := s.requestContext()
+		req = req.WithContext(ctx)
+		watch := func() {
+			if req.BodyDone() {
+				cr.startBackgroundRead(cancel)
+			}
+		}
+		if req.BodyDone() {
+			watch()
+		} else {
+			req.BodyReader = &bodyEOFSignal{ReadCloser: req.BodyReader, onEOF: watch}
+		}
+
+		w := response.NewWriter(conn)
+		w.SetVersion(req.RequestLine.Major, req.RequestLine.Minor)
+		w.SetMethod(req.RequestLine.Method)
+		w.SetKeepAlive(req.KeepAlive() && !s.closed.Load())
+		s.handler(w, req)
+		cr.abortPendingRead()
+		disconnected := errors.Is(context.Cause(ctx), ErrClientDisconnected)
+		cancel(context.Canceled)
+		if req.MultipartForm != nil {
+			req.MultipartForm.RemoveAll()
+		}
+		if disconnected {
+			return
+		}
+		if err := w.Finish(); err != nil || !w.KeepAlive() {
+			return
+		}
+		if err := req.Discard(maxDiscardBytes); err != nil {
+			return
+		}
 	}
-	s.handler(w, request)
+}
+
+// requestContext derives the context for one request from the server's, with
+// the configured timeout.
+func (s *Server) requestContext() (context.Context, context.CancelCauseFunc) {
+	ctx, cancel := context.WithCancelCause(s.baseCtx)
+	if s.config.RequestTimeout <= 0 {
+		return ctx, cancel
+	}
+	timeoutCtx, cancelTimeout := context.WithTimeout(ctx, s.config.RequestTimeout)
+	return timeoutCtx, func(cause error) {
+		cancel(cause)
+		cancelTimeout()
+	}
+}
+
+func statusForError(err error) response.StatusCode {
+	switch {
+	case errors.Is(err, request.ErrRequestLineTooLong):
+		return response.HttpURITooLong
+	case errors.Is(err, request.ErrHeadersTooLarge), errors.Is(err, request.ErrTooManyHeaders):
+		return response.HttpRequestHeaderFieldsTooLarge
+	case errors.Is(err, request.ErrBodyTooLong):
+		return response.HttpContentTooLarge
+	case errors.Is(err, request.ErrUnsupportedVersion):
+		return response.HttpVersionNotSupported
+	default:
+		return response.HttpNotFoud
+	}
+}
+
+func writeError(w *response.Writer, statusCode response.StatusCode) {
+	w.SetKeepAlive(false)
+	w.WriteStatusLine(statusCode)
+	w.WriteHeaders(response.GetDefaultHeaders(0))
 }
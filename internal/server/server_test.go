@@ -0,0 +1,396 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"httpfromtcp/internal/headers"
+	"httpfromtcp/internal/request"
+	"httpfromtcp/internal/response"
+	"io"
+	"math/big"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func echoTargetHandler(w *response.Writer, req *request.Request) {
+	body := []byte(req.RequestLine.RequestTarget)
+	w.WriteStatusLine(response.HttpOK)
+	w.WriteHeaders(response.GetDefaultHeaders(len(body)))
+	w.WriteBody(body)
+}
+
+func startTestServer(t *testing.T, h Handler) net.Conn {
+	_, conn := startTestServerWithConfig(t, h, DefaultConfig())
+	return conn
+}
+
+func startTestServerWithConfig(t *testing.T, h Handler, config Config) (*Server, net.Conn) {
+	s, err := ServeWithConfig(0, h, config)
+	require.NoError(t, err)
+	t.Cleanup(func() { s.Close() })
+	conn, err := net.Dial("tcp", s.listener.Addr().String())
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	return s, conn
+}
+
+func readResponse(t *testing.T, r *bufio.Reader) (string, map[string]string, string) {
+	statusLine, err := r.ReadString('\n')
+	require.NoError(t, err)
+	h := map[string]string{}
+	for {
+		line, err := r.ReadString('\n')
+		require.NoError(t, err)
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			break
+		}
+		key, value, _ := strings.Cut(line, ":")
+		h[strings.ToLower(key)] = strings.TrimSpace(value)
+	}
+	var body string
+	if cl, ok := h["content-length"]; ok {
+		n, err := strconv.Atoi(cl)
+		require.NoError(t, err)
+		b := make([]byte, n)
+		_, err = io.ReadFull(r, b)
+		require.NoError(t, err)
+		body = string(b)
+	}
+	return strings.TrimRight(statusLine, "\r\n"), h, body
+}
+
+func TestKeepAlive(t *testing.T) {
+	// Test: Two requests on one connection
+	conn := startTestServer(t, echoTargetHandler)
+	r := bufio.NewReader(conn)
+	_, err := conn.Write([]byte("GET /one HTTP/1.1\r\nHost: localhost\r\n\r\n"))
+	require.NoError(t, err)
+	status, h, body := readResponse(t, r)
+	assert.Equal(t, "HTTP/1.1 200 OK", status)
+	assert.Equal(t, "/one", body)
+	assert.NotContains(t, h, "connection")
+
+	_, err = conn.Write([]byte("GET /two HTTP/1.1\r\nHost: localhost\r\nConnection: close\r\n\r\n"))
+	require.NoError(t, err)
+	_, h, body = readResponse(t, r)
+	assert.Equal(t, "/two", body)
+	assert.Equal(t, "close", h["connection"])
+	_, err = r.ReadByte()
+	assert.ErrorIs(t, err, io.EOF)
+
+	// Test: Pipelined requests with an unread body in between
+	conn = startTestServer(t, echoTargetHandler)
+	r = bufio.NewReader(conn)
+	_, err = conn.Write([]byte("POST /a HTTP/1.1\r\nHost: localhost\r\nContent-Length: 5\r\n\r\nhello" +
+		"GET /b HTTP/1.1\r\nHost: localhost\r\n\r\n" +
+		"GET /c HTTP/1.1\r\nHost: localhost\r\nConnection: close\r\n\r\n"))
+	require.NoError(t, err)
+	for _, want := range []string{"/a", "/b", "/c"} {
+		_, _, body = readResponse(t, r)
+		assert.Equal(t, want, body)
+	}
+	_, err = r.ReadByte()
+	assert.ErrorIs(t, err, io.EOF)
+
+	// Test: Response without framing closes the connection
+	conn = startTestServer(t, func(w *response.Writer, req *request.Request) {
+		w.WriteStatusLine(response.HttpOK)
+		h := response.GetDefaultHeaders(0)
+		h.Del("Content-Length")
+		w.WriteHeaders(h)
+		w.WriteBody([]byte("unframed"))
+	})
+	r = bufio.NewReader(conn)
+	_, err = conn.Write([]byte("GET / HTTP/1.1\r\nHost: localhost\r\n\r\n"))
+	require.NoError(t, err)
+	_, h, _ = readResponse(t, r)
+	assert.Equal(t, "close", h["connection"])
+	rest, err := io.ReadAll(r)
+	require.NoError(t, err)
+	assert.Equal(t, "unframed", string(rest))
+
+	// Test: Pipelined HEAD then GET stay in step
+	conn = startTestServer(t, echoTargetHandler)
+	r = bufio.NewReader(conn)
+	_, err = conn.Write([]byte("HEAD /a HTTP/1.1\r\nHost: localhost\r\n\r\n" +
+		"GET /b HTTP/1.1\r\nHost: localhost\r\nConnection: close\r\n\r\n"))
+	require.NoError(t, err)
+	statusLine, err := r.ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, "HTTP/1.1 200 OK\r\n", statusLine)
+	for {
+		line, err := r.ReadString('\n')
+		require.NoError(t, err)
+		if line == "\r\n" {
+			break
+		}
+		if strings.HasPrefix(strings.ToLower(line), "content-length:") {
+			assert.Equal(t, "2", strings.TrimSpace(line[len("content-length:"):]))
+		}
+	}
+	_, _, body = readResponse(t, r)
+	assert.Equal(t, "/b", body)
+}
+
+func TestHTTP10(t *testing.T) {
+	// Test: HTTP/1.0 closes by default and answers in kind
+	conn := startTestServer(t, echoTargetHandler)
+	r := bufio.NewReader(conn)
+	_, err := conn.Write([]byte("GET /old HTTP/1.0\r\n\r\n"))
+	require.NoError(t, err)
+	status, h, body := readResponse(t, r)
+	assert.Equal(t, "HTTP/1.0 200 OK", status)
+	assert.Equal(t, "close", h["connection"])
+	assert.Equal(t, "/old", body)
+	_, err = r.ReadByte()
+	assert.ErrorIs(t, err, io.EOF)
+
+	// Test: HTTP/1.0 keep-alive
+	conn = startTestServer(t, echoTargetHandler)
+	r = bufio.NewReader(conn)
+	_, err = conn.Write([]byte("GET /a HTTP/1.0\r\nConnection: keep-alive\r\n\r\nGET /b HTTP/1.0\r\n\r\n"))
+	require.NoError(t, err)
+	_, h, body = readResponse(t, r)
+	assert.Equal(t, "keep-alive", h["connection"])
+	assert.Equal(t, "/a", body)
+	_, h, body = readResponse(t, r)
+	assert.Equal(t, "close", h["connection"])
+	assert.Equal(t, "/b", body)
+
+	// Test: Chunked responses are sent unframed
+	conn = startTestServer(t, func(w *response.Writer, req *request.Request) {
+		w.WriteStatusLine(response.HttpOK)
+		h := response.GetDefaultHeaders(0)
+		h.Del("Content-Length")
+		h.Set("Transfer-Encoding", "chunked")
+		h.Set("Trailer", "X-Content-Length")
+		w.WriteHeaders(h)
+		w.WriteChunkedBody([]byte("hello "))
+		w.WriteChunkedBody([]byte("world"))
+		w.WriteChunkedBodyEnd()
+		trailers := headers.NewHeaders()
+		trailers.Set("X-Content-Length", "11")
+		w.WriteTrailers(trailers)
+	})
+	r = bufio.NewReader(conn)
+	_, err = conn.Write([]byte("GET / HTTP/1.0\r\n\r\n"))
+	require.NoError(t, err)
+	_, h, _ = readResponse(t, r)
+	assert.NotContains(t, h, "transfer-encoding")
+	assert.NotContains(t, h, "trailer")
+	rest, err := io.ReadAll(r)
+	require.NoError(t, err)
+	assert.Equal(t, "hello world", string(rest))
+
+	// Test: Unknown versions get 505
+	conn = startTestServer(t, echoTargetHandler)
+	r = bufio.NewReader(conn)
+	_, err = conn.Write([]byte("GET / HTTP/2.0\r\nHost: localhost\r\n\r\n"))
+	require.NoError(t, err)
+	status, _, _ = readResponse(t, r)
+	assert.Equal(t, "HTTP/1.1 505 HTTP Version Not Supported", status)
+}
+
+func TestBodyTooLarge(t *testing.T) {
+	config := DefaultConfig()
+	config.Parser.MaxBodyBytes = 8
+	_, conn := startTestServerWithConfig(t, echoTargetHandler, config)
+	r := bufio.NewReader(conn)
+	_, err := conn.Write([]byte("POST / HTTP/1.1\r\nHost: localhost\r\nContent-Length: 9\r\n\r\n"))
+	require.NoError(t, err)
+	status, h, _ := readResponse(t, r)
+	assert.Equal(t, "HTTP/1.1 413 Content Too Large", status)
+	assert.Equal(t, "close", h["connection"])
+}
+
+func TestRequestContext(t *testing.T) {
+	// waitHandler reports why the request context ended, then answers.
+	waitHandler := func(causes chan<- error) Handler {
+		return func(w *response.Writer, req *request.Request) {
+			select {
+			case <-req.Context().Done():
+				causes <- context.Cause(req.Context())
+			case <-time.After(5 * time.Second):
+				causes <- nil
+			}
+			echoTargetHandler(w, req)
+		}
+	}
+
+	// Test: Client hangs up
+	causes := make(chan error, 1)
+	conn := startTestServer(t, waitHandler(causes))
+	_, err := conn.Write([]byte("GET /gone HTTP/1.1\r\nHost: localhost\r\n\r\n"))
+	require.NoError(t, err)
+	conn.Close()
+	require.ErrorIs(t, receive(t, causes), ErrClientDisconnected)
+
+	// Test: Client hangs up after sending a body the handler reads
+	causes = make(chan error, 1)
+	started := make(chan string, 1)
+	bodies := make(chan string, 1)
+	readErrs := make(chan error, 1)
+	conn = startTestServer(t, func(w *response.Writer, req *request.Request) {
+		started <- req.RequestLine.RequestTarget
+		body, err := io.ReadAll(req.BodyReader)
+		bodies <- string(body)
+		readErrs <- err
+		waitHandler(causes)(w, req)
+	})
+	_, err = conn.Write([]byte("POST /gone HTTP/1.1\r\nHost: localhost\r\nContent-Length: 5\r\n\r\nhel"))
+	require.NoError(t, err)
+	assert.Equal(t, "/gone", receive(t, started))
+	_, err = conn.Write([]byte("lo"))
+	require.NoError(t, err)
+	require.NoError(t, receive(t, readErrs))
+	assert.Equal(t, "hello", receive(t, bodies))
+	conn.Close()
+	require.ErrorIs(t, receive(t, causes), ErrClientDisconnected)
+
+	// Test: Request timeout
+	config := DefaultConfig()
+	config.RequestTimeout = 20 * time.Millisecond
+	causes = make(chan error, 1)
+	_, conn = startTestServerWithConfig(t, waitHandler(causes), config)
+	r := bufio.NewReader(conn)
+	_, err = conn.Write([]byte("GET /slow HTTP/1.1\r\nHost: localhost\r\n\r\n"))
+	require.NoError(t, err)
+	require.ErrorIs(t, receive(t, causes), context.DeadlineExceeded)
+	_, _, body := readResponse(t, r)
+	assert.Equal(t, "/slow", body)
+
+	// Test: Server shutdown
+	causes = make(chan error, 1)
+	started = make(chan string, 1)
+	s, conn := startTestServerWithConfig(t, func(w *response.Writer, req *request.Request) {
+		started <- req.RequestLine.RequestTarget
+		waitHandler(causes)(w, req)
+	}, DefaultConfig())
+	_, err = conn.Write([]byte("GET /shutdown HTTP/1.1\r\nHost: localhost\r\n\r\n"))
+	require.NoError(t, err)
+	assert.Equal(t, "/shutdown", receive(t, started))
+	s.Close()
+	require.ErrorIs(t, receive(t, causes), ErrServerClosed)
+
+	// Test: Pipelined requests do not cancel the one being handled
+	started = make(chan string, 2)
+	ctxErrs := make(chan error, 2)
+	release := make(chan struct{})
+	conn = startTestServer(t, func(w *response.Writer, req *request.Request) {
+		started <- req.RequestLine.RequestTarget
+		<-release
+		ctxErrs <- req.Context().Err()
+		echoTargetHandler(w, req)
+	})
+	r = bufio.NewReader(conn)
+	_, err = conn.Write([]byte("GET /a HTTP/1.1\r\nHost: localhost\r\n\r\n"))
+	require.NoError(t, err)
+	assert.Equal(t, "/a", receive(t, started))
+	_, err = conn.Write([]byte("GET /b HTTP/1.1\r\nHost: localhost\r\n\r\n"))
+	require.NoError(t, err)
+	close(release)
+	for _, want := range []string{"/a", "/b"} {
+		_, _, body = readResponse(t, r)
+		assert.Equal(t, want, body)
+		assert.NoError(t, receive(t, ctxErrs), want)
+	}
+	assert.Equal(t, "/b", receive(t, started))
+}
+
+// receive waits for the next value a handler sends on ch, so that results
+// from the server's goroutines are checked on the test's own.
+func receive[T any](t *testing.T, ch <-chan T) T {
+	t.Helper()
+	select {
+	case v := <-ch:
+		return v
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the handler")
+	}
+	var zero T
+	return zero
+}
+
+func TestConnectionMetadata(t *testing.T) {
+	requests := make(chan *request.Request, 2)
+	handler := func(w *response.Writer, req *request.Request) {
+		requests <- req
+		echoTargetHandler(w, req)
+	}
+
+	// Test: Plain connection
+	before := time.Now()
+	_, conn := startTestServerWithConfig(t, handler, DefaultConfig())
+	r := bufio.NewReader(conn)
+	for _, target := range []string{"/one", "/two"} {
+		_, err := conn.Write([]byte("GET " + target + " HTTP/1.1\r\nHost: localhost\r\n\r\n"))
+		require.NoError(t, err)
+		readResponse(t, r)
+	}
+	for sequence := 1; sequence <= 2; sequence++ {
+		req := <-requests
+		assert.Equal(t, conn.LocalAddr().String(), req.RemoteAddr)
+		assert.Equal(t, conn.RemoteAddr().String(), req.LocalAddr)
+		assert.False(t, req.AcceptedAt.Before(before))
+		assert.Equal(t, sequence, req.Sequence)
+		assert.Nil(t, req.TLS)
+	}
+
+	// Test: TLS connection
+	config := DefaultConfig()
+	config.TLSConfig = &tls.Config{Certificates: []tls.Certificate{testCertificate(t)}}
+	s, err := ServeWithConfig(0, handler, config)
+	require.NoError(t, err)
+	t.Cleanup(func() { s.Close() })
+	tlsConn, err := tls.Dial("tcp", s.listener.Addr().String(), &tls.Config{
+		InsecureSkipVerify: true,
+		ServerName:         "localhost",
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { tlsConn.Close() })
+	tlsConn.SetDeadline(time.Now().Add(5 * time.Second))
+	_, err = tlsConn.Write([]byte("GET /secure HTTP/1.1\r\nHost: localhost\r\n\r\n"))
+	require.NoError(t, err)
+	status, _, body := readResponse(t, bufio.NewReader(tlsConn))
+	assert.Equal(t, "HTTP/1.1 200 OK", status)
+	assert.Equal(t, "/secure", body)
+
+	req := <-requests
+	require.NotNil(t, req.TLS)
+	assert.True(t, req.TLS.HandshakeComplete)
+	assert.Equal(t, "localhost", req.TLS.ServerName)
+	assert.Equal(t, tlsConn.ConnectionState().Version, req.TLS.Version)
+	assert.Equal(t, 1, req.Sequence)
+}
+
+// testCertificate makes a self-signed certificate for localhost.
+func testCertificate(t *testing.T) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}